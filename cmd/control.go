@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/control"
+	"github.com/urfave/cli/v2"
+)
+
+// socketPath resolves the socket flag, falling back to the default socket
+func socketPath(ctx *cli.Context) string {
+	socketPath := ctx.String("socket")
+
+	if socketPath == "" {
+		socketPath = control.DefaultSocketPath()
+	}
+
+	return socketPath
+}
+
+// call sends the request to the running daemon, an error is
+// returned as a cli.ExitCoder so that it is printed without usage
+func call(ctx *cli.Context, req control.Request) (*control.Response, error) {
+	res, err := control.Call(socketPath(ctx), req)
+	if err != nil {
+		return nil, cli.Exit(err.Error(), 1)
+	}
+	return res, nil
+}
+
+// serviceCommand constructs a command that operates on the service named by its argument
+func serviceCommand(command control.Command, usage string, done string) *cli.Command {
+	return &cli.Command{
+		Name:      string(command),
+		Usage:     usage,
+		ArgsUsage: "<service>",
+		Action: func(ctx *cli.Context) error {
+			if ctx.NArg() != 1 {
+				return cli.Exit("exactly one service must be provided", 1)
+			}
+
+			service := ctx.Args().First()
+			_, err := call(ctx, control.Request{Command: command, Service: service})
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s: %s\n", service, done)
+			return nil
+		},
+	}
+}
+
+var controlCommands = []*cli.Command{
+	{
+		Name:  "status",
+		Usage: "Show the health of the running daemon",
+		Action: func(ctx *cli.Context) error {
+			res, err := call(ctx, control.Request{Command: control.StatusCommand})
+			if err != nil {
+				return err
+			}
+			if res.Status == nil {
+				return errors.New("daemon did not return a status")
+			}
+
+			status := res.Status
+
+			fmt.Printf("Started: %s (up %s)\n\n", status.Started.Format(time.RFC3339), status.Uptime.Round(time.Second))
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "POOL\tBUSY\tQUEUED\tDROPPED")
+			for _, pool := range append([]control.PoolStatus{status.Pool}, status.Pools...) {
+				fmt.Fprintf(w, "%s\t%d/%d\t%d\t%d\n", pool.Name, pool.Busy, pool.Size, pool.Queued, pool.Dropped)
+			}
+			fmt.Fprintln(w)
+
+			fmt.Fprintln(w, "WATCHER\tSTATE\tDETAIL")
+			for _, watcher := range status.Watchers {
+				detail := watcher.Detail
+				if watcher.Error != "" {
+					detail = watcher.Error
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", watcher.Name, watcher.State, detail)
+			}
+			return w.Flush()
+		},
+	},
+	{
+		Name:  "list",
+		Usage: "List the services of the running daemon and their last result",
+		Action: func(ctx *cli.Context) error {
+			res, err := call(ctx, control.Request{Command: control.ListCommand})
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "SERVICE\tCONDITIONS\tEXECUTOR\tSTATE\tSKIPPED\tCANCELLED\tLAST RESULT")
+			for _, svc := range res.Services {
+				state := "active"
+				if svc.Paused {
+					state = "paused"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+					svc.Name,
+					strings.Join(svc.Conditions, ", "),
+					svc.Executor,
+					state,
+					svc.Skipped,
+					svc.Cancelled,
+					describeResult(svc.LastResult))
+			}
+			return w.Flush()
+		},
+	},
+	serviceCommand(control.TriggerCommand, "Run the executor of a service of the running daemon now", "triggered"),
+	serviceCommand(control.PauseCommand, "Ignore the conditions of a service of the running daemon until resumed", "paused"),
+	serviceCommand(control.ResumeCommand, "Resume a paused service of the running daemon", "resumed"),
+}
+
+// describeResult summarises the result of an execution for display
+func describeResult(result *control.Result) string {
+	if result == nil {
+		return "-"
+	}
+
+	outcome := "ok"
+	if result.Error != "" {
+		outcome = "failed: " + result.Error
+	}
+
+	return fmt.Sprintf("%s at %s (%s)", outcome, result.Started.Format(time.RFC3339), result.Duration.Round(time.Millisecond))
+}
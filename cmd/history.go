@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/executor"
+	"github.com/mickyco94/saucisson/internal/history"
+	"github.com/urfave/cli/v2"
+)
+
+// historyDir resolves the history directory from the config,
+// falling back to the default if the config does not set one
+func historyDir(ctx *cli.Context) string {
+	if dir := ctx.String("dir"); dir != "" {
+		return dir
+	}
+
+	file, err := os.Open(configPath(ctx))
+	if err == nil {
+		defer file.Close()
+
+		//A config with problems may still set the directory
+		cfg := &config.Raw{}
+		cfg.Parse(file)
+		if cfg.History.Dir != "" {
+			return cfg.History.Dir
+		}
+	}
+
+	return history.DefaultDir()
+}
+
+// parseSince accepts a duration before now, e.g. 1h, or a time in RFC 3339
+func parseSince(since string) (time.Time, error) {
+	if since == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a duration, e.g. 1h, or a time in RFC 3339", since)
+	}
+	return t, nil
+}
+
+var historyCommand = &cli.Command{
+	Name:  "history",
+	Usage: "Show the executions recorded in the history, oldest first",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "service",
+			Usage: "Only show executions of the service",
+		},
+		&cli.BoolFlag{
+			Name:  "failed",
+			Usage: "Only show executions that failed or were cancelled",
+		},
+		&cli.StringFlag{
+			Name:  "since",
+			Usage: "Only show executions started since a duration ago, e.g. 1h, or a time in RFC 3339",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Write each execution as a line of JSON, including the event and output",
+		},
+		&cli.StringFlag{
+			Name:  "dir",
+			Usage: "Directory of the history. Defaults to history.dir of the config, or $XDG_STATE_HOME/saucisson",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		since, err := parseSince(ctx.String("since"))
+		if err != nil {
+			return cli.Exit(err.Error(), 1)
+		}
+
+		entries, err := history.Read(historyDir(ctx), history.Filter{
+			Service: ctx.String("service"),
+			Failed:  ctx.Bool("failed"),
+			Since:   since,
+		})
+		if err != nil {
+			return cli.Exit(err.Error(), 1)
+		}
+
+		if ctx.Bool("json") {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, entry := range entries {
+				err := encoder.Encode(entry)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSERVICE\tCONDITION\tSTARTED\tDURATION\tRESULT\tERROR")
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				entry.ID,
+				entry.Service,
+				entry.Event.Condition,
+				entry.Started.Format(time.RFC3339),
+				entry.Duration.Round(time.Millisecond),
+				describeEntryResult(entry),
+				describeError(entry))
+		}
+		return w.Flush()
+	},
+}
+
+// describeEntryResult is the result of an entry, along with whether its
+// commands had to be stopped
+func describeEntryResult(entry history.Entry) string {
+	switch entry.Termination {
+	case executor.Terminated, executor.Killed:
+		return fmt.Sprintf("%s (%s)", entry.Result, entry.Termination)
+	default:
+		return string(entry.Result)
+	}
+}
+
+// describeError summarises the error of an entry on a single line
+func describeError(entry history.Entry) string {
+	if entry.Error == "" {
+		return "-"
+	}
+
+	return strings.ReplaceAll(entry.Error, "\n", " ")
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/history"
+	"github.com/urfave/cli/v2"
+)
+
+// followInterval is how often logs are checked for new output when following
+const followInterval = 500 * time.Millisecond
+
+// printLog writes the log at path to stdout from offset, returning the offset
+// that was read to. A log that has been removed has nothing more to read
+func printLog(path string, offset int64) (int64, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return offset, nil
+	}
+	if err != nil {
+		return offset, err
+	}
+	defer file.Close()
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return offset, err
+	}
+
+	n, err := io.Copy(os.Stdout, file)
+	return offset + n, err
+}
+
+// followLogs writes the output of the log at path as it is written, and
+// then of each newer log of the service, until interrupted
+func followLogs(dir string, service string, path string, offset int64) error {
+	seen := make(map[string]bool)
+
+	logs, err := history.ListLogs(dir, service)
+	if err != nil {
+		return err
+	}
+	for _, log := range logs {
+		seen[log.ID] = true
+	}
+
+	for {
+		time.Sleep(followInterval)
+
+		if path != "" {
+			offset, err = printLog(path, offset)
+			if err != nil {
+				return err
+			}
+		}
+
+		logs, err := history.ListLogs(dir, service)
+		if err != nil {
+			return err
+		}
+
+		for _, log := range logs {
+			if seen[log.ID] {
+				continue
+			}
+			seen[log.ID] = true
+
+			fmt.Printf("==> job %s <==\n", log.ID)
+			path = log.Path
+			offset, err = printLog(path, 0)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+var logsCommand = &cli.Command{
+	Name:      "logs",
+	Usage:     "Show the output of the latest job of a service",
+	ArgsUsage: "<service>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "job",
+			Usage: "Show the output of the job with the id instead, see saucisson history",
+		},
+		&cli.BoolFlag{
+			Name:    "follow",
+			Aliases: []string{"f"},
+			Usage:   "Keep writing output as it is written, including the output of later jobs",
+		},
+		&cli.StringFlag{
+			Name:  "dir",
+			Usage: "Directory of the history. Defaults to history.dir of the config, or $XDG_STATE_HOME/saucisson",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		if ctx.NArg() != 1 {
+			return cli.Exit("expected the name of a service", 1)
+		}
+		service := ctx.Args().First()
+		dir := historyDir(ctx)
+
+		path := ""
+		if id := ctx.String("job"); id != "" {
+			path = filepath.Join(history.LogDir(dir, service), id+".log")
+			if _, err := os.Stat(path); err != nil {
+				return cli.Exit(fmt.Sprintf("no log of job %s of %s", id, service), 1)
+			}
+		} else {
+			logs, err := history.ListLogs(dir, service)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+			if len(logs) > 0 {
+				path = logs[len(logs)-1].Path
+			} else if !ctx.Bool("follow") {
+				return cli.Exit(fmt.Sprintf("no logs of %s", service), 1)
+			}
+		}
+
+		var offset int64
+		if path != "" {
+			var err error
+			offset, err = printLog(path, 0)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+		}
+
+		if !ctx.Bool("follow") {
+			return nil
+		}
+
+		err := followLogs(dir, service, path, offset)
+		if err != nil {
+			return cli.Exit(err.Error(), 1)
+		}
+		return nil
+	},
+}
@@ -1,15 +1,51 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path"
 
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/executor"
 	"github.com/mickyco94/saucisson/internal/runner"
 	"github.com/urfave/cli/v2"
 )
 
+// configPath resolves the config flag, falling back to ~/.saucisson.yml
+func configPath(ctx *cli.Context) string {
+	configPath := ctx.String("config")
+
+	if configPath == "" {
+		homedir := os.Getenv("HOME")
+		configPath = path.Join(homedir, ".saucisson.yml")
+	}
+
+	return configPath
+}
+
+// printConfigErrors writes each configuration problem on its own line,
+// prefixed with the path of the file so that editors can jump to it
+func printConfigErrors(configPath string, err error) {
+	var errs config.Errors
+	if !errors.As(err, &errs) {
+		log.Printf("%s: %s", configPath, err.Error())
+		return
+	}
+
+	for _, e := range errs {
+		if e.Line == 0 {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", configPath, e.Error())
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s:%s\n", configPath, e.Error())
+	}
+}
+
 func main() {
+	//Executors with limits run saucisson to apply them before their command
+	executor.RunLimited()
 
 	app := &cli.App{
 		Name:  "saucisson",
@@ -18,21 +54,47 @@ func main() {
 			Name:    "config",
 			Aliases: []string{"c"},
 			Usage:   "Path of the saucisson definition YAML file. Defaults to ~/.saucisson.yml",
+		}, &cli.StringFlag{
+			Name:    "socket",
+			Aliases: []string{"s"},
+			Usage:   "Path of the control socket of the running daemon. Defaults to $XDG_RUNTIME_DIR/saucisson.sock",
 		}},
 		Description: "Saucisson is a background service that uses provided configuration to run specified procedures when the specified condition(s) are met.",
 		Action:      cli.ShowAppHelp,
-		Commands: []*cli.Command{
+		Commands: append([]*cli.Command{
 			{
-				Name: "run",
+				Name:  "run",
+				Usage: "Validate the config and run the defined services until interrupted",
+				Flags: []cli.Flag{&cli.BoolFlag{
+					Name:    "watch",
+					Aliases: []string{"w"},
+					Usage:   "Reload the config whenever the config file is updated. SIGHUP always reloads the config",
+				}, &cli.StringFlag{
+					Name:  "log-level",
+					Usage: "Level of logs written, one of trace, debug, info, warn or error. Overrides logging.level",
+				}, &cli.StringFlag{
+					Name:  "log-format",
+					Usage: "Format of logs, one of text, json or logfmt. Overrides logging.format",
+				}, &cli.StringFlag{
+					Name:  "log-output",
+					Usage: "Destination of logs, stderr, syslog or a file path. Overrides logging.output",
+				}},
 				Action: func(ctx *cli.Context) error {
-					configPath := ctx.String("config")
+					configPath := configPath(ctx)
 
-					if configPath == "" {
-						homedir := os.Getenv("HOME")
-						configPath = path.Join(homedir, ".saucisson.yml")
+					err := runner.Validate(configPath)
+					if err != nil {
+						printConfigErrors(configPath, err)
+						return cli.Exit("", 1)
 					}
 
-					err := runner.Run(configPath)
+					err = runner.Run(configPath, runner.Options{
+						WatchConfig: ctx.Bool("watch"),
+						Socket:      socketPath(ctx),
+						LogLevel:    ctx.String("log-level"),
+						LogFormat:   ctx.String("log-format"),
+						LogOutput:   ctx.String("log-output"),
+					})
 					if err != nil {
 						log.Printf(err.Error())
 						return err
@@ -40,7 +102,25 @@ func main() {
 					return nil
 				},
 			},
-		},
+			{
+				Name:  "validate",
+				Usage: "Check the config for errors without running any services",
+				Action: func(ctx *cli.Context) error {
+					configPath := configPath(ctx)
+
+					err := runner.Validate(configPath)
+					if err != nil {
+						printConfigErrors(configPath, err)
+						return cli.Exit("", 1)
+					}
+
+					fmt.Printf("%s: OK\n", configPath)
+					return nil
+				},
+			},
+			historyCommand,
+			logsCommand,
+		}, controlCommands...),
 	}
 
 	app.Run(os.Args)
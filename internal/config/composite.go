@@ -0,0 +1,63 @@
+package config
+
+import "time"
+
+// Composite conditions combine any number of child conditions, of any type,
+// into a single condition
+const (
+	AnyKey      Condition = "any"
+	AllKey      Condition = "all"
+	SequenceKey Condition = "sequence"
+)
+
+// Any is satisfied whenever any one of its child conditions is satisfied
+type Any struct {
+	Conditions []ComponentSpec `yaml:"conditions"`
+}
+
+// Validate checks that there is at least one child condition
+func (any *Any) Validate() []error {
+	if len(any.Conditions) == 0 {
+		return []error{Required("conditions")}
+	}
+	return nil
+}
+
+// All is satisfied once every one of its child conditions has been
+// satisfied, in any order, within the window
+type All struct {
+	Window     time.Duration   `yaml:"window"`
+	Conditions []ComponentSpec `yaml:"conditions"`
+}
+
+// Validate checks that there is at least one child condition and a window
+func (all *All) Validate() []error {
+	return validateWindowed(all.Window, all.Conditions)
+}
+
+// Sequence is satisfied once each of its child conditions has been
+// satisfied in the order they are defined, with the first and last
+// occurring within the window
+type Sequence struct {
+	Window     time.Duration   `yaml:"window"`
+	Conditions []ComponentSpec `yaml:"conditions"`
+}
+
+// Validate checks that there is at least one child condition and a window
+func (sequence *Sequence) Validate() []error {
+	return validateWindowed(sequence.Window, sequence.Conditions)
+}
+
+func validateWindowed(window time.Duration, conditions []ComponentSpec) []error {
+	var errs []error
+
+	if window <= 0 {
+		errs = append(errs, Invalid("window", "must be a positive duration, e.g. 30s"))
+	}
+
+	if len(conditions) == 0 {
+		errs = append(errs, Required("conditions"))
+	}
+
+	return errs
+}
@@ -0,0 +1,54 @@
+package config
+
+// ConcurrencyPolicy determines what happens when a service is triggered
+// while a previous execution of it is still running
+type ConcurrencyPolicy string
+
+const (
+	// Allow runs every trigger immediately, executions may overlap
+	Allow ConcurrencyPolicy = "allow"
+	// Skip drops the trigger
+	Skip ConcurrencyPolicy = "skip"
+	// Queue runs the trigger once the running execution, and any
+	// triggers queued before it, have completed
+	Queue ConcurrencyPolicy = "queue"
+	// Replace cancels the running execution and runs the trigger
+	Replace ConcurrencyPolicy = "replace"
+)
+
+// Concurrency is the overlap policy of a service. The default policy is Allow.
+// With the Allow policy, MaxConcurrent limits the number of executions that
+// run at once, further triggers wait as with the Queue policy.
+// MaxDepth limits the number of triggers waiting
+type Concurrency struct {
+	Policy        ConcurrencyPolicy `yaml:"policy"`
+	MaxConcurrent int               `yaml:"max_concurrent"`
+	MaxDepth      int               `yaml:"max_depth"`
+}
+
+// Validate checks the policy is known and the limits are only used where they apply
+func (c *Concurrency) Validate() []error {
+	var errs []error
+
+	switch c.Policy {
+	case "", Allow, Skip, Queue, Replace:
+	default:
+		errs = append(errs, Invalid("policy", "%q is not one of allow, skip, queue or replace", c.Policy))
+	}
+
+	allow := c.Policy == "" || c.Policy == Allow
+
+	if c.MaxConcurrent < 0 {
+		errs = append(errs, Invalid("max_concurrent", "must not be negative"))
+	} else if c.MaxConcurrent > 0 && !allow {
+		errs = append(errs, Invalid("max_concurrent", "can only be used with the allow policy"))
+	}
+
+	if c.MaxDepth < 0 {
+		errs = append(errs, Invalid("max_depth", "must not be negative"))
+	} else if c.MaxDepth > 0 && c.Policy != Queue && !(allow && c.MaxConcurrent > 0) {
+		errs = append(errs, Invalid("max_depth", "can only be used with the queue policy or max_concurrent"))
+	}
+
+	return errs
+}
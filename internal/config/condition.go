@@ -1,5 +1,9 @@
 package config
 
+import (
+	"github.com/robfig/cron/v3"
+)
+
 // Condition is the identifier for condition types that can be found in config:
 type Condition string
 
@@ -25,6 +29,25 @@ type Cron struct {
 	Schedule string `yaml:"schedule"`
 }
 
+// cronParser mirrors the parser used by the cron watcher, seconds are required
+var cronParser = cron.NewParser(
+	cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// Validate checks that the schedule is a valid cron spec
+func (c *Cron) Validate() []error {
+	if c.Schedule == "" {
+		return []error{Required("schedule")}
+	}
+
+	_, err := cronParser.Parse(c.Schedule)
+	if err != nil {
+		return []error{Invalid("schedule", "is not a valid cron spec: %v", err)}
+	}
+
+	return nil
+}
+
 // File defines the path and change operation applied to that path that
 // the file condition should watch for
 type File struct {
@@ -32,6 +55,25 @@ type File struct {
 	Path      string    `yaml:"path"`
 }
 
+// Validate checks that a path is provided and the operation is supported
+func (f *File) Validate() []error {
+	var errs []error
+
+	if f.Path == "" {
+		errs = append(errs, Required("path"))
+	}
+
+	switch f.Operation {
+	case Create, Update, Remove, Rename:
+	case "":
+		errs = append(errs, Required("operation"))
+	default:
+		errs = append(errs, Invalid("operation", "%q is not one of create, update, remove or rename", f.Operation))
+	}
+
+	return errs
+}
+
 // State refers to the state change of a running process, i.e. open/close
 type State string
 
@@ -46,3 +88,22 @@ type Process struct {
 	Executable string `yaml:"executable"`
 	State      State  `yaml:"state"`
 }
+
+// Validate checks that an executable is provided and the state is supported
+func (p *Process) Validate() []error {
+	var errs []error
+
+	if p.Executable == "" {
+		errs = append(errs, Required("executable"))
+	}
+
+	switch p.State {
+	case Open, Close:
+	case "":
+		errs = append(errs, Required("state"))
+	default:
+		errs = append(errs, Invalid("state", "%q is not one of open or close", p.State))
+	}
+
+	return errs
+}
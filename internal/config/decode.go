@@ -0,0 +1,293 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Error is a problem found in the configuration, positioned at the line and
+// column of the YAML node that caused it. A Line of 0 means the position is unknown.
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (err *Error) Error() string {
+	if err.Line == 0 {
+		return err.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", err.Line, err.Column, err.Message)
+}
+
+// Errorf constructs an Error positioned at the provided node, node may be nil
+func Errorf(node *yaml.Node, format string, args ...any) *Error {
+	err := &Error{Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		err.Line = node.Line
+		err.Column = node.Column
+	}
+	return err
+}
+
+// Errors is a collection of configuration problems, reported together so that
+// a single validation pass surfaces everything that is wrong with a file.
+type Errors []*Error
+
+func (errs Errors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Err returns nil if there are no errors, this avoids returning a typed nil
+// as an error interface.
+func (errs Errors) Err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// FieldError is a semantic problem with a single field of a spec.
+// Decode positions it at the value of that field when present in the YAML.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (err *FieldError) Error() string {
+	return fmt.Sprintf("%s %s", err.Field, err.Message)
+}
+
+// Required constructs a FieldError for a missing mandatory field
+func Required(field string) *FieldError {
+	return &FieldError{Field: field, Message: "is required"}
+}
+
+// Invalid constructs a FieldError for a field with an unacceptable value
+func Invalid(field string, format string, args ...any) *FieldError {
+	return &FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// Validator is implemented by specs that can check their own semantics once
+// they have been decoded, e.g. required fields or enumerations.
+type Validator interface {
+	Validate() []error
+}
+
+// Decode strictly decodes node into out. Unlike yaml.Node.Decode, unknown keys
+// are rejected and, if out implements Validator, its semantic checks are run.
+// Every problem found is returned, positioned at the offending node.
+func Decode(node *yaml.Node, out any) Errors {
+	errs := checkFields(node, reflect.TypeOf(out))
+
+	if node.Kind != 0 {
+		err := node.Decode(out)
+		// Semantic checks on a partially decoded struct would only add noise
+		if err != nil {
+			return append(errs, decodeErrors(node, err)...)
+		}
+	}
+
+	validator, ok := out.(Validator)
+	if !ok {
+		return errs
+	}
+
+	return append(errs, validate(node, validator)...)
+}
+
+// validate runs the semantic checks of validator, positioning each problem
+// at the field of node that it relates to, otherwise at node itself
+func validate(node *yaml.Node, validator Validator) Errors {
+	var errs Errors
+
+	for _, err := range validator.Validate() {
+		switch err := err.(type) {
+		case *Error:
+			errs = append(errs, err)
+		case *FieldError:
+			value := lookup(node, err.Field)
+			if value == nil {
+				value = resolve(node)
+			}
+			errs = append(errs, Errorf(value, "%s", err.Error()))
+		default:
+			errs = append(errs, Errorf(resolve(node), "%s", err.Error()))
+		}
+	}
+
+	return errs
+}
+
+var nodeType = reflect.TypeOf(yaml.Node{})
+
+// checkFields walks node alongside the Go type it will be decoded into,
+// reporting any mapping keys that have no corresponding field.
+func checkFields(node *yaml.Node, t reflect.Type) Errors {
+	node = resolve(node)
+	if node == nil || t == nil {
+		return nil
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var errs Errors
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t == nodeType || node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		fields := make(map[string]reflect.Type)
+		collectFields(t, fields)
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			fieldType, known := fields[key.Value]
+			if !known {
+				errs = append(errs, Errorf(key, "unknown field %q", key.Value))
+				continue
+			}
+			errs = append(errs, checkFields(value, fieldType)...)
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for _, item := range node.Content {
+			errs = append(errs, checkFields(item, t.Elem())...)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			errs = append(errs, checkFields(node.Content[i], t.Elem())...)
+		}
+	}
+
+	return errs
+}
+
+// collectFields maps the yaml key of each field of t to the field type,
+// flattening inlined structs
+func collectFields(t reflect.Type, fields map[string]reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("yaml")
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if name == "-" {
+			continue
+		}
+
+		if strings.Contains(opts, "inline") {
+			inner := field.Type
+			for inner.Kind() == reflect.Pointer {
+				inner = inner.Elem()
+			}
+			if inner.Kind() == reflect.Struct {
+				collectFields(inner, fields)
+			}
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fields[name] = field.Type
+	}
+}
+
+// resolve follows document and alias nodes to the node holding content
+func resolve(node *yaml.Node) *yaml.Node {
+	for node != nil {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			if len(node.Content) == 0 {
+				return nil
+			}
+			node = node.Content[0]
+		case yaml.AliasNode:
+			node = node.Alias
+		default:
+			return node
+		}
+	}
+	return nil
+}
+
+// lookup returns the value node of the provided key in a mapping node
+func lookup(node *yaml.Node, key string) *yaml.Node {
+	node = resolve(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// decodeErrors converts the errors produced by the yaml package into
+// positioned Errors. yaml.TypeError embeds the line in each message.
+func decodeErrors(node *yaml.Node, err error) Errors {
+	typeErr, ok := err.(*yaml.TypeError)
+	if !ok {
+		return Errors{Errorf(node, "%s", err.Error())}
+	}
+
+	errs := make(Errors, 0, len(typeErr.Errors))
+	for _, message := range typeErr.Errors {
+		var line int
+		_, scanErr := fmt.Sscanf(message, "line %d:", &line)
+		if scanErr != nil {
+			errs = append(errs, Errorf(node, "%s", message))
+			continue
+		}
+
+		_, rest, _ := strings.Cut(message, ": ")
+		position := atLine(node, line)
+		if position == nil {
+			position = &yaml.Node{Line: line, Column: 1}
+		}
+		errs = append(errs, Errorf(position, "%s", rest))
+	}
+	return errs
+}
+
+// atLine finds the first node within node that begins on the provided line
+func atLine(node *yaml.Node, line int) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	if node.Line == line && node.Kind != yaml.DocumentNode {
+		return node
+	}
+	for _, child := range node.Content {
+		found := atLine(child, line)
+		if found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t *testing.T, input string) Errors {
+	t.Helper()
+
+	cfg := &Raw{}
+	err := cfg.Parse(strings.NewReader(input))
+	if err == nil {
+		return nil
+	}
+
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("expected config.Errors, got %v", err)
+	}
+	return errs
+}
+
+func TestParseValid(t *testing.T) {
+	errs := parse(t, `
+services:
+  - name: cron
+    condition:
+      type: cron
+      config:
+        schedule: "*/10 * * * * *"
+    execute:
+      type: shell
+      config:
+        command: echo done
+`)
+
+	assert.Empty(t, errs)
+}
+
+func TestParseUnknownField(t *testing.T) {
+	errs := parse(t, `
+services:
+  - name: cron
+    conditon:
+      type: cron
+`)
+
+	assert.Len(t, errs, 1)
+	assert.Equal(t, 4, errs[0].Line)
+	assert.Equal(t, 5, errs[0].Column)
+	assert.Contains(t, errs[0].Message, "conditon")
+}
+
+func TestParseDuplicateNames(t *testing.T) {
+	errs := parse(t, `
+services:
+  - name: a
+  - name: a
+  - condition: {}
+`)
+
+	assert.Len(t, errs, 2)
+	assert.Equal(t, 4, errs[0].Line)
+	assert.Contains(t, errs[0].Message, "already in use")
+	assert.Equal(t, 5, errs[1].Line)
+	assert.Contains(t, errs[1].Message, "name is required")
+}
+
+func TestDecodeComponent(t *testing.T) {
+	cfg := &Raw{}
+	err := cfg.Parse(strings.NewReader(`
+services:
+  - name: file
+    condition:
+      type: file
+      config:
+        operation: chmod
+        pth: /tmp
+`))
+	assert.NoError(t, err)
+
+	errs := cfg.Services[0].Condition.Decode(&File{})
+
+	assert.Len(t, errs, 3)
+	assert.Equal(t, 8, errs[0].Line)
+	assert.Contains(t, errs[0].Message, `unknown field "pth"`)
+	assert.Equal(t, 7, errs[1].Line)
+	assert.Contains(t, errs[1].Message, "path is required")
+	assert.Equal(t, 7, errs[2].Line)
+	assert.Equal(t, 20, errs[2].Column)
+	assert.Contains(t, errs[2].Message, "chmod")
+}
+
+func TestDecodeTypeError(t *testing.T) {
+	cfg := &Raw{}
+	err := cfg.Parse(strings.NewReader(`
+services:
+  - name: cron
+    condition:
+      type: cron
+      config:
+        schedule: [1, 2]
+`))
+	assert.NoError(t, err)
+
+	errs := cfg.Services[0].Condition.Decode(&Cron{})
+
+	assert.Len(t, errs, 1)
+	assert.Equal(t, 7, errs[0].Line)
+}
+
+func TestCronValidate(t *testing.T) {
+	assert.Empty(t, (&Cron{Schedule: "*/10 * * * * *"}).Validate())
+	assert.Len(t, (&Cron{Schedule: "* * * * *"}).Validate(), 1)
+	assert.Len(t, (&Cron{}).Validate(), 1)
+}
+
+func TestValidateRetry(t *testing.T) {
+	cfg := &Raw{}
+	err := cfg.Parse(strings.NewReader(`
+services:
+  - name: retry
+    execute:
+      type: shell
+      retry:
+        initial_backoff: 1s
+        max_backoff: 500ms
+        on:
+          http_status: ["5xx", "6xx"]
+`))
+	assert.NoError(t, err)
+
+	errs := cfg.Services[0].Execute.ValidateRetry()
+
+	assert.Len(t, errs, 2)
+	assert.Equal(t, 8, errs[0].Line)
+	assert.Contains(t, errs[0].Message, "initial_backoff")
+	assert.Equal(t, 10, errs[1].Line)
+	assert.Contains(t, errs[1].Message, "6xx")
+}
+
+func TestStatusRangeBounds(t *testing.T) {
+	for input, expected := range map[StatusRange][2]int{
+		"429":     {429, 429},
+		"500-504": {500, 504},
+		"5xx":     {500, 599},
+	} {
+		min, max, err := input.Bounds()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, [2]int{min, max}, input)
+	}
+
+	for _, input := range []StatusRange{"", "600", "504-500", "5x"} {
+		_, _, err := input.Bounds()
+		assert.Error(t, err, input)
+	}
+}
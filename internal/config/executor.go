@@ -0,0 +1,11 @@
+package config
+
+// Executor is the identifier for executor types that can be found in config:
+type Executor string
+
+const (
+	ShellKey Executor = "shell"
+	HttpKey  Executor = "http"
+	SpawnKey Executor = "spawn"
+	ExecKey  Executor = "exec"
+)
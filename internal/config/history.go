@@ -0,0 +1,63 @@
+package config
+
+import "time"
+
+// History determines where the result of each execution is recorded and
+// for how long. Entries are removed once they are older than MaxAge, and the
+// oldest entries are removed once the history is larger than MaxSize.
+// The output of each execution is truncated to MaxOutput, in full it is
+// written to the logs of the job, see JobLogs.
+//
+// Zero values are replaced by the defaults
+type History struct {
+	Disabled  bool          `yaml:"disabled"`
+	Dir       string        `yaml:"dir"`
+	MaxSize   ByteSize      `yaml:"max_size"`
+	MaxAge    time.Duration `yaml:"max_age"`
+	MaxOutput ByteSize      `yaml:"max_output"`
+	Logs      JobLogs       `yaml:"logs"`
+}
+
+// JobLogs determines how long the output of each job is kept in the history
+// directory. The logs of a service are removed once they are older than
+// MaxAge, and the oldest are removed once a service has more than MaxFiles.
+// Output beyond MaxSize is not written to the log of a job.
+//
+// Zero values are replaced by the defaults
+type JobLogs struct {
+	Disabled bool          `yaml:"disabled"`
+	MaxAge   time.Duration `yaml:"max_age"`
+	MaxFiles int           `yaml:"max_files"`
+	MaxSize  ByteSize      `yaml:"max_size"`
+}
+
+// Validate checks that the limits are not negative
+func (history *History) Validate() []error {
+	var errs []error
+
+	if history.MaxSize < 0 {
+		errs = append(errs, Invalid("max_size", "must not be negative"))
+	}
+
+	if history.MaxAge < 0 {
+		errs = append(errs, Invalid("max_age", "must not be negative"))
+	}
+
+	if history.MaxOutput < 0 {
+		errs = append(errs, Invalid("max_output", "must not be negative"))
+	}
+
+	if history.Logs.MaxAge < 0 {
+		errs = append(errs, Invalid("logs", "max_age must not be negative"))
+	}
+
+	if history.Logs.MaxFiles < 0 {
+		errs = append(errs, Invalid("logs", "max_files must not be negative"))
+	}
+
+	if history.Logs.MaxSize < 0 {
+		errs = append(errs, Invalid("logs", "max_size must not be negative"))
+	}
+
+	return errs
+}
@@ -0,0 +1,81 @@
+package config
+
+// LogFormat is the format that log entries are written in
+type LogFormat string
+
+const (
+	// TextFormat is readable in a terminal, and logfmt otherwise
+	TextFormat LogFormat = "text"
+	JsonFormat LogFormat = "json"
+	// LogfmtFormat writes each entry as key=value pairs
+	LogfmtFormat LogFormat = "logfmt"
+)
+
+const (
+	// StderrOutput and SyslogOutput are the outputs of logs that are not a file
+	StderrOutput = "stderr"
+	SyslogOutput = "syslog"
+)
+
+// logLevels are the accepted log levels, from least to most verbose
+var logLevels = []string{"panic", "fatal", "error", "warn", "warning", "info", "debug", "trace"}
+
+// Logging determines the level, format and output of logs. Output is stderr,
+// syslog, which writes to the local syslog socket, or the path of a file.
+// A file is rotated once it reaches MaxSize, keeping MaxBackups rotated files.
+// Services overrides the level for the entries of individual services, by name
+type Logging struct {
+	Level      string            `yaml:"level"`
+	Format     LogFormat         `yaml:"format"`
+	Output     string            `yaml:"output"`
+	MaxSize    ByteSize          `yaml:"max_size"`
+	MaxBackups int               `yaml:"max_backups"`
+	Services   map[string]string `yaml:"services"`
+}
+
+// Validate checks that the levels and format are known and that
+// rotation is only configured for a file
+func (logging *Logging) Validate() []error {
+	var errs []error
+
+	if logging.Level != "" && !validLogLevel(logging.Level) {
+		errs = append(errs, Invalid("level", "%q is not one of trace, debug, info, warn or error", logging.Level))
+	}
+
+	switch logging.Format {
+	case "", TextFormat, JsonFormat, LogfmtFormat:
+	default:
+		errs = append(errs, Invalid("format", "%q is not one of text, json or logfmt", logging.Format))
+	}
+
+	file := logging.Output != "" && logging.Output != StderrOutput && logging.Output != SyslogOutput
+
+	if logging.MaxSize < 0 {
+		errs = append(errs, Invalid("max_size", "must not be negative"))
+	} else if logging.MaxSize > 0 && !file {
+		errs = append(errs, Invalid("max_size", "can only be used when output is a file"))
+	}
+
+	if logging.MaxBackups < 0 {
+		errs = append(errs, Invalid("max_backups", "must not be negative"))
+	} else if logging.MaxBackups > 0 && logging.MaxSize == 0 {
+		errs = append(errs, Invalid("max_backups", "can only be used with max_size"))
+	}
+
+	for service, level := range logging.Services {
+		if !validLogLevel(level) {
+			errs = append(errs, Invalid("services", "%s: %q is not one of trace, debug, info, warn or error", service, level))
+		}
+	}
+
+	return errs
+}
+
+func validLogLevel(level string) bool {
+	for _, valid := range logLevels {
+		if level == valid {
+			return true
+		}
+	}
+	return false
+}
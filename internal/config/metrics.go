@@ -0,0 +1,7 @@
+package config
+
+// MetricsServer configures the HTTP listener that serves metrics
+// on /metrics. The listener is only started if Address is set
+type MetricsServer struct {
+	Address string `yaml:"address"`
+}
@@ -1,40 +1,216 @@
 package config
 
 import (
+	"errors"
 	"io"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Raw is the unprocessed configuration specification for the saucisson service
 type Raw struct {
-	Services []ServiceSpec `yaml:"services"`
+	// Vars are available to the templated fields of every executor
+	Vars    map[string]any `yaml:"vars"`
+	Webhook WebhookServer  `yaml:"webhook"`
+	Metrics MetricsServer  `yaml:"metrics"`
+	Logging Logging        `yaml:"logging"`
+	// Pool is the shared executor pool, Pools are named pools
+	// that services can use to isolate their executions
+	Pool     Pool            `yaml:"pool"`
+	Pools    map[string]Pool `yaml:"pools"`
+	History  History         `yaml:"history"`
+	Services []ServiceSpec   `yaml:"services"`
 }
 
 // ServiceSpec is a structural definition of a service configuration,
-// mirroring exactly how it is defined in YAML
+// mirroring exactly how it is defined in YAML.
+// A service has either a single Condition or a list of Conditions,
+// the service is executed whenever any one of the Conditions is satisfied.
+// Similarly a service either has a single executor, Execute, or a pipeline
+// of Steps. OnFailure and Finally steps can be used with either.
+// Concurrency determines how overlapping executions are handled.
+// Pool names the executor pool that runs the service, the shared pool if empty.
+// Threshold, Debounce and Throttle, in that order, limit how often the
+// events of the conditions trigger the service. DebounceMaxWait bounds how
+// long Debounce holds events back.
 type ServiceSpec struct {
-	Name      string        `yaml:"name"`
-	Condition ComponentSpec `yaml:"condition"`
-	Execute   ComponentSpec `yaml:"execute"`
+	Name        string          `yaml:"name"`
+	Condition   ComponentSpec   `yaml:"condition"`
+	Conditions  []ComponentSpec `yaml:"conditions"`
+	Execute     ComponentSpec   `yaml:"execute"`
+	Steps       []StepSpec      `yaml:"steps"`
+	OnFailure   []StepSpec      `yaml:"on_failure"`
+	Finally     []StepSpec      `yaml:"finally"`
+	Concurrency Concurrency     `yaml:"concurrency"`
+	Pool        string          `yaml:"pool"`
+	Threshold   *Threshold      `yaml:"threshold"`
+	Debounce    time.Duration   `yaml:"debounce"`
+	Throttle    time.Duration   `yaml:"throttle"`
+
+	DebounceMaxWait time.Duration `yaml:"debounce_max_wait"`
+
+	node *yaml.Node
+}
+
+// UnmarshalYAML decodes the spec and retains its node for error reporting
+func (spec *ServiceSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain ServiceSpec
+	err := node.Decode((*plain)(spec))
+	if err != nil {
+		return err
+	}
+
+	spec.node = node
+	return nil
+}
+
+// Errorf constructs an Error positioned at the service definition
+func (spec *ServiceSpec) Errorf(format string, args ...any) *Error {
+	return Errorf(spec.node, format, args...)
 }
 
 // ComponentSpec is a generic struct that corresponds
-// to a condition or executor element in the YAML specification
+// to a condition or executor element in the YAML specification.
+// Retry is only valid for executors
 type ComponentSpec struct {
 	Type   Condition `yaml:"type"`
 	Config yaml.Node `yaml:"config"`
+	Retry  *Retry    `yaml:"retry"`
+
+	node *yaml.Node
+}
+
+// UnmarshalYAML decodes the spec and retains its node for error reporting
+func (spec *ComponentSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain ComponentSpec
+	err := node.Decode((*plain)(spec))
+	if err != nil {
+		return err
+	}
+
+	spec.node = node
+	return nil
+}
+
+// IsZero reports whether the component was omitted from the YAML
+func (spec *ComponentSpec) IsZero() bool {
+	return spec.node == nil
+}
+
+// Errorf constructs an Error positioned at the component definition
+func (spec *ComponentSpec) Errorf(format string, args ...any) *Error {
+	return Errorf(spec.node, format, args...)
+}
+
+// TypeErrorf constructs an Error positioned at the type of the component
+func (spec *ComponentSpec) TypeErrorf(format string, args ...any) *Error {
+	typeNode := lookup(spec.node, "type")
+	if typeNode == nil {
+		typeNode = spec.node
+	}
+	return Errorf(typeNode, format, args...)
+}
+
+// ValidateRetry runs the semantic checks of Retry, if present,
+// positioning each problem within the retry definition
+func (spec *ComponentSpec) ValidateRetry() Errors {
+	if spec.Retry == nil {
+		return nil
+	}
+	return validate(lookup(spec.node, "retry"), spec.Retry)
 }
 
-// Parse reads config from the specified reader into the struct
+// Decode strictly decodes the config of the component into out, see Decode
+func (spec *ComponentSpec) Decode(out any) Errors {
+	if spec.Config.Kind == 0 {
+		errs := Errors{}
+		// Still run the semantic checks so missing fields are reported
+		if validator, ok := out.(Validator); ok {
+			for _, err := range validator.Validate() {
+				errs = append(errs, spec.Errorf("config: %s", err.Error()))
+			}
+		}
+		return errs
+	}
+
+	return Decode(&spec.Config, out)
+}
+
+// Parse reads config from the specified reader into the struct.
+// Unknown keys are rejected and all structural problems are returned
+// as Errors.
 func (r *Raw) Parse(reader io.Reader) error {
+	root := &yaml.Node{}
 
 	decoder := yaml.NewDecoder(reader)
-	err := decoder.Decode(r)
+	err := decoder.Decode(root)
+
+	if errors.Is(err, io.EOF) {
+		return Errors{{Message: "config is empty"}}
+	}
 
 	if err != nil {
 		return err
 	}
 
-	return nil
+	errs := Decode(root, r)
+
+	return errs.Err()
+}
+
+// Validate checks that every service is named, and named uniquely,
+// and that the pools, history and logging are usable
+func (r *Raw) Validate() []error {
+	var errs []error
+
+	names := make(map[string]struct{})
+
+	for i := range r.Services {
+		spec := &r.Services[i]
+
+		if spec.Name == "" {
+			errs = append(errs, spec.Errorf("service name is required"))
+			continue
+		}
+
+		if _, exists := names[spec.Name]; exists {
+			errs = append(errs, spec.Errorf("service name %q is already in use", spec.Name))
+		}
+		names[spec.Name] = struct{}{}
+	}
+
+	for _, err := range r.Pool.Validate() {
+		errs = append(errs, Invalid("pool", "%s", err.Error()))
+	}
+
+	for _, err := range r.History.Validate() {
+		errs = append(errs, Invalid("history", "%s", err.Error()))
+	}
+
+	for _, err := range r.Logging.Validate() {
+		errs = append(errs, Invalid("logging", "%s", err.Error()))
+	}
+
+	for service := range r.Logging.Services {
+		if _, exists := names[service]; !exists {
+			errs = append(errs, Invalid("logging", "services: %q is not a service", service))
+		}
+	}
+
+	for name, pool := range r.Pools {
+		if name == DefaultPool {
+			errs = append(errs, Invalid("pools", "%q is reserved for the shared pool", name))
+		}
+
+		if pool.Size == 0 {
+			errs = append(errs, Invalid("pools", "%s: size is required", name))
+		}
+
+		for _, err := range pool.Validate() {
+			errs = append(errs, Invalid("pools", "%s: %s", name, err.Error()))
+		}
+	}
+
+	return errs
 }
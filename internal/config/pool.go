@@ -0,0 +1,46 @@
+package config
+
+// Overflow determines what happens to a job that is triggered
+// while the queue of the executor pool is full
+type Overflow string
+
+const (
+	// Block waits for space in the queue, delaying the watcher that triggered the job
+	Block Overflow = "block"
+	// DropNewest drops the job that was triggered
+	DropNewest Overflow = "drop-newest"
+	// DropOldest drops the job that has been queued the longest to make room
+	DropOldest Overflow = "drop-oldest"
+)
+
+// DefaultPool is the name of the shared executor pool
+const DefaultPool = "default"
+
+// Pool configures an executor pool of Size workers. Jobs wait for a worker
+// in a queue of QueueDepth jobs, Overflow determines what happens once it is full
+type Pool struct {
+	Size       int      `yaml:"size"`
+	QueueDepth int      `yaml:"queue_depth"`
+	Overflow   Overflow `yaml:"overflow"`
+}
+
+// Validate checks that the size and queue depth are usable and the overflow policy is known
+func (p *Pool) Validate() []error {
+	var errs []error
+
+	if p.Size < 0 {
+		errs = append(errs, Invalid("size", "must be a positive number of workers"))
+	}
+
+	if p.QueueDepth < 0 {
+		errs = append(errs, Invalid("queue_depth", "must be a positive number of jobs"))
+	}
+
+	switch p.Overflow {
+	case "", Block, DropNewest, DropOldest:
+	default:
+		errs = append(errs, Invalid("overflow", "%q is not one of block, drop-newest or drop-oldest", p.Overflow))
+	}
+
+	return errs
+}
@@ -0,0 +1,25 @@
+package config
+
+import "time"
+
+// Threshold only satisfies the conditions of a service once they
+// have been satisfied Count times within Window
+type Threshold struct {
+	Count  int           `yaml:"count"`
+	Window time.Duration `yaml:"window"`
+}
+
+// Validate checks that the count and window are usable
+func (threshold *Threshold) Validate() []error {
+	var errs []error
+
+	if threshold.Count <= 0 {
+		errs = append(errs, Invalid("count", "must be a positive number of events"))
+	}
+
+	if threshold.Window <= 0 {
+		errs = append(errs, Invalid("window", "must be a positive duration, e.g. 30s"))
+	}
+
+	return errs
+}
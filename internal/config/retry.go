@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retry determines how an executor that failed is retried.
+// MaxAttempts includes the first attempt. The delay before each retry starts
+// at InitialBackoff and is multiplied by Multiplier after every attempt, up
+// to MaxBackoff. Jitter randomly shortens each delay by up to that fraction.
+// No retry is started once MaxElapsed would be exceeded by its delay, as
+// the worker running the executor is held for the whole time.
+//
+// Zero values are replaced by the defaults, see Defaults
+type Retry struct {
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	Multiplier     float64       `yaml:"multiplier"`
+	Jitter         float64       `yaml:"jitter"`
+	MaxElapsed     time.Duration `yaml:"max_elapsed"`
+	On             RetryOn       `yaml:"on"`
+}
+
+// RetryOn selects the errors that are retried. If nothing is selected
+// every error is retried
+type RetryOn struct {
+	// Timeout retries executors that exceeded their timeout
+	Timeout bool `yaml:"timeout"`
+	// ExitCodes retries commands that exited with one of the codes
+	ExitCodes []int `yaml:"exit_codes"`
+	// HttpStatus retries HTTP requests that failed with a status in one of the ranges
+	HttpStatus []StatusRange `yaml:"http_status"`
+}
+
+// IsZero reports whether nothing is selected
+func (on *RetryOn) IsZero() bool {
+	return !on.Timeout && len(on.ExitCodes) == 0 && len(on.HttpStatus) == 0
+}
+
+// Defaults returns a copy of retry with zero values replaced by the defaults:
+// 3 attempts, an initial backoff of 1s doubling up to 30s, no jitter and
+// at most 5m spent retrying
+func (retry Retry) Defaults() Retry {
+	if retry.MaxAttempts == 0 {
+		retry.MaxAttempts = 3
+	}
+	if retry.InitialBackoff == 0 {
+		retry.InitialBackoff = time.Second
+	}
+	if retry.MaxBackoff == 0 {
+		retry.MaxBackoff = 30 * time.Second
+	}
+	if retry.Multiplier == 0 {
+		retry.Multiplier = 2
+	}
+	if retry.MaxElapsed == 0 {
+		retry.MaxElapsed = 5 * time.Minute
+	}
+	return retry
+}
+
+// Validate checks that the backoff is usable and the status ranges can be parsed
+func (retry *Retry) Validate() []error {
+	var errs []error
+
+	if retry.MaxAttempts < 0 {
+		errs = append(errs, Invalid("max_attempts", "must be a positive number of attempts"))
+	}
+
+	if retry.InitialBackoff < 0 {
+		errs = append(errs, Invalid("initial_backoff", "must not be negative"))
+	}
+
+	if retry.MaxBackoff < 0 {
+		errs = append(errs, Invalid("max_backoff", "must not be negative"))
+	} else if retry.MaxBackoff > 0 && retry.MaxBackoff < retry.InitialBackoff {
+		errs = append(errs, Invalid("max_backoff", "must not be less than initial_backoff"))
+	}
+
+	if retry.Multiplier != 0 && retry.Multiplier < 1 {
+		errs = append(errs, Invalid("multiplier", "must be at least 1"))
+	}
+
+	if retry.MaxElapsed < 0 {
+		errs = append(errs, Invalid("max_elapsed", "must not be negative"))
+	}
+
+	if retry.Jitter < 0 || retry.Jitter > 1 {
+		errs = append(errs, Invalid("jitter", "must be between 0 and 1"))
+	}
+
+	for _, status := range retry.On.HttpStatus {
+		if _, _, err := status.Bounds(); err != nil {
+			errs = append(errs, Invalid("on", "http_status %v", err))
+		}
+	}
+
+	return errs
+}
+
+// StatusRange is an inclusive range of HTTP status codes,
+// written as a single code, e.g. 429, a range, e.g. 500-504, or a class, e.g. 5xx
+type StatusRange string
+
+// Bounds returns the lowest and highest status codes in the range
+func (r StatusRange) Bounds() (int, int, error) {
+	s := strings.ToLower(strings.TrimSpace(string(r)))
+
+	if len(s) == 3 && strings.HasSuffix(s, "xx") && s[0] >= '1' && s[0] <= '5' {
+		class := int(s[0]-'0') * 100
+		return class, class + 99, nil
+	}
+
+	low, high, isRange := strings.Cut(s, "-")
+	if !isRange {
+		high = low
+	}
+
+	min, minErr := strconv.Atoi(strings.TrimSpace(low))
+	max, maxErr := strconv.Atoi(strings.TrimSpace(high))
+
+	if minErr != nil || maxErr != nil || min < 100 || max > 599 || min > max {
+		return 0, 0, fmt.Errorf("%q is not a status code, range of codes or class of codes", string(r))
+	}
+
+	return min, max, nil
+}
+
+// Contains reports whether status is in the range, an invalid range contains nothing
+func (r StatusRange) Contains(status int) bool {
+	min, max, err := r.Bounds()
+	return err == nil && status >= min && status <= max
+}
@@ -0,0 +1,82 @@
+package config
+
+import "gopkg.in/yaml.v3"
+
+// StepSpec is a single executor within a pipeline of steps.
+// Type, Config and Retry are defined exactly as they would be for execute.
+type StepSpec struct {
+	Name            string    `yaml:"name"`
+	Type            Condition `yaml:"type"`
+	Config          yaml.Node `yaml:"config"`
+	Retry           *Retry    `yaml:"retry"`
+	ContinueOnError bool      `yaml:"continue_on_error"`
+	When            *When     `yaml:"when"`
+
+	node *yaml.Node
+}
+
+// UnmarshalYAML decodes the spec and retains its node for error reporting
+func (spec *StepSpec) UnmarshalYAML(node *yaml.Node) error {
+	type plain StepSpec
+	err := node.Decode((*plain)(spec))
+	if err != nil {
+		return err
+	}
+
+	spec.node = node
+	return nil
+}
+
+// Component returns the executor of the step as a ComponentSpec
+func (spec *StepSpec) Component() ComponentSpec {
+	return ComponentSpec{
+		Type:   spec.Type,
+		Config: spec.Config,
+		Retry:  spec.Retry,
+		node:   spec.node,
+	}
+}
+
+// Errorf constructs an Error positioned at the step definition
+func (spec *StepSpec) Errorf(format string, args ...any) *Error {
+	return Errorf(spec.node, format, args...)
+}
+
+// StepStatus is the outcome of a step that has been run or skipped
+type StepStatus string
+
+const (
+	Success StepStatus = "success"
+	Failure StepStatus = "failure"
+	Skipped StepStatus = "skipped"
+)
+
+// When makes a step conditional on the outcome of an earlier step.
+// The step is run if the earlier step has the specified Status and,
+// if specified, ExitCode. Otherwise it is skipped.
+type When struct {
+	Step     string     `yaml:"step"`
+	Status   StepStatus `yaml:"status"`
+	ExitCode *int       `yaml:"exit_code"`
+}
+
+// Validate checks that an earlier step is referenced and the status is known
+func (when *When) Validate() []error {
+	var errs []error
+
+	if when.Step == "" {
+		errs = append(errs, Required("step"))
+	}
+
+	switch when.Status {
+	case "", Success, Failure, Skipped:
+	default:
+		errs = append(errs, Invalid("status", "%q is not one of success, failure or skipped", when.Status))
+	}
+
+	if when.Status == "" && when.ExitCode == nil {
+		errs = append(errs, Invalid("status", "or exit_code is required"))
+	}
+
+	return errs
+}
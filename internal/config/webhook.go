@@ -0,0 +1,70 @@
+package config
+
+import (
+	"net/http"
+	"strings"
+)
+
+const WebhookKey Condition = "webhook"
+
+// Verification is the method used to verify that a webhook request
+// was sent by a holder of the shared secret
+type Verification string
+
+const (
+	// Token requires the secret to be sent verbatim in a header
+	Token Verification = "token"
+	// HmacSha256 requires a hex encoded HMAC-SHA256 signature of the body,
+	// prefixed with "sha256=", to be sent in a header. This is the scheme
+	// used by GitHub's X-Hub-Signature-256
+	HmacSha256 Verification = "hmac-sha256"
+)
+
+// WebhookServer configures the HTTP listener shared by every webhook condition.
+// The listener is only started if Address is set
+type WebhookServer struct {
+	Address string `yaml:"address"`
+}
+
+// Webhook defines a HTTP endpoint, hosted by the webhook server,
+// that satisfies the condition when it receives a request
+type Webhook struct {
+	Path    string       `yaml:"path"`
+	Methods []string     `yaml:"methods"`
+	Secret  string       `yaml:"secret"`
+	Verify  Verification `yaml:"verify"`
+	Header  string       `yaml:"header"`
+}
+
+// Validate checks the path and methods, and that a secret is provided
+// if verification is required
+func (w *Webhook) Validate() []error {
+	var errs []error
+
+	if w.Path == "" {
+		errs = append(errs, Required("path"))
+	} else if !strings.HasPrefix(w.Path, "/") {
+		errs = append(errs, Invalid("path", "must begin with /"))
+	}
+
+	for _, method := range w.Methods {
+		switch method {
+		case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+			http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		default:
+			errs = append(errs, Invalid("methods", "%q is not a HTTP method", method))
+		}
+	}
+
+	switch w.Verify {
+	case "":
+	case Token, HmacSha256:
+		if w.Secret == "" {
+			errs = append(errs, Required("secret"))
+		}
+	default:
+		errs = append(errs, Invalid("verify", "%q is not one of token or hmac-sha256", w.Verify))
+	}
+
+	return errs
+}
@@ -0,0 +1,47 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ErrNotRunning is returned by Call when there is no daemon listening
+var ErrNotRunning = errors.New("saucisson is not running")
+
+// Call sends req to the daemon listening on the socket at path
+// and returns the response. A response with Error set is returned
+// as an error
+func Call(path string, req Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", path, connectionTimeout)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ECONNREFUSED) {
+		return nil, fmt.Errorf("%w, no daemon is listening on %s", ErrNotRunning, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(connectionTimeout))
+
+	err = json.NewEncoder(conn).Encode(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Response{}
+	err = json.NewDecoder(conn).Decode(res)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,112 @@
+// Package control implements the protocol spoken between a running
+// saucisson daemon and its clients over a Unix socket.
+//
+// Each connection carries a single JSON encoded Request from the client,
+// answered by a single JSON encoded Response from the daemon.
+package control
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Command identifies the operation requested of the daemon
+type Command string
+
+const (
+	// StatusCommand reports the health of the daemon
+	StatusCommand Command = "status"
+	// ListCommand reports every service
+	ListCommand Command = "list"
+	// TriggerCommand runs the executor of a service immediately
+	TriggerCommand Command = "trigger"
+	// PauseCommand stops the conditions of a service from running its executor
+	PauseCommand Command = "pause"
+	// ResumeCommand reverses PauseCommand
+	ResumeCommand Command = "resume"
+)
+
+// Request is sent by the client, Service is only required by commands
+// that operate on a single service
+type Request struct {
+	Command Command `json:"command"`
+	Service string  `json:"service,omitempty"`
+}
+
+// Response is returned by the daemon. Error is set if the request failed,
+// otherwise the field corresponding to the command is set, if any
+type Response struct {
+	Error    string          `json:"error,omitempty"`
+	Status   *Status         `json:"status,omitempty"`
+	Services []ServiceStatus `json:"services,omitempty"`
+}
+
+// Status describes the daemon as a whole
+type Status struct {
+	Started  time.Time       `json:"started"`
+	Uptime   time.Duration   `json:"uptime"`
+	Watchers []WatcherStatus `json:"watchers"`
+	Pool     PoolStatus      `json:"pool"`
+	Pools    []PoolStatus    `json:"pools,omitempty"`
+}
+
+// WatcherState is the health of a watcher
+type WatcherState string
+
+const (
+	Running  WatcherState = "running"
+	Failed   WatcherState = "failed"
+	Disabled WatcherState = "disabled"
+)
+
+// WatcherStatus describes one of the watchers that satisfy conditions
+type WatcherStatus struct {
+	Name  string       `json:"name"`
+	State WatcherState `json:"state"`
+	// Detail is additional information about the watcher, e.g. an address
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// PoolStatus describes the utilisation of an executor pool
+type PoolStatus struct {
+	Name    string `json:"name"`
+	Size    int    `json:"size"`
+	Busy    int    `json:"busy"`
+	Queued  int    `json:"queued"`
+	Dropped uint64 `json:"dropped"`
+}
+
+// ServiceStatus describes a single service
+type ServiceStatus struct {
+	Name       string   `json:"name"`
+	Conditions []string `json:"conditions"`
+	Executor   string   `json:"executor"`
+	Paused     bool     `json:"paused"`
+	// Skipped and Cancelled count the triggers not run, or cancelled,
+	// due to the concurrency policy of the service
+	Skipped    uint64  `json:"skipped"`
+	Cancelled  uint64  `json:"cancelled"`
+	LastResult *Result `json:"last_result,omitempty"`
+}
+
+// Result is the outcome of the most recent execution of a service
+type Result struct {
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// DefaultSocketPath is the path of the socket when one is not configured.
+// The socket is created in $XDG_RUNTIME_DIR if set, otherwise in a
+// directory private to the user under the temporary directory
+func DefaultSocketPath() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = filepath.Join(os.TempDir(), fmt.Sprintf("saucisson-%d", os.Getuid()))
+	}
+
+	return filepath.Join(runtimeDir, "saucisson.sock")
+}
@@ -0,0 +1,77 @@
+package control
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func echoServer(t *testing.T) (*Server, string) {
+	path := filepath.Join(t.TempDir(), "saucisson.sock")
+
+	server := NewServer(logrus.New(), HandlerFunc(func(req Request) Response {
+		if req.Command == PauseCommand {
+			return Response{Error: "cannot pause " + req.Service}
+		}
+		return Response{Services: []ServiceStatus{{Name: req.Service}}}
+	}))
+
+	err := server.Listen(path)
+	assert.Nil(t, err)
+
+	t.Cleanup(func() { server.Stop(context.Background()) })
+
+	return server, path
+}
+
+func TestCall(t *testing.T) {
+	_, path := echoServer(t)
+
+	res, err := Call(path, Request{Command: ListCommand, Service: "svc"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "svc", res.Services[0].Name)
+}
+
+func TestCallError(t *testing.T) {
+	_, path := echoServer(t)
+
+	_, err := Call(path, Request{Command: PauseCommand, Service: "svc"})
+
+	assert.EqualError(t, err, "cannot pause svc")
+}
+
+func TestCallNotRunning(t *testing.T) {
+	_, err := Call(filepath.Join(t.TempDir(), "saucisson.sock"), Request{Command: StatusCommand})
+
+	assert.ErrorIs(t, err, ErrNotRunning)
+}
+
+func TestListenInUse(t *testing.T) {
+	_, path := echoServer(t)
+
+	err := NewServer(logrus.New(), nil).Listen(path)
+
+	assert.ErrorContains(t, err, "in use")
+}
+
+func TestListenStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "saucisson.sock")
+
+	listener, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+
+	server := NewServer(logrus.New(), HandlerFunc(func(req Request) Response { return Response{} }))
+	err = server.Listen(path)
+	assert.Nil(t, err)
+	server.Stop(context.Background())
+
+	_, err = Call(path, Request{Command: StatusCommand})
+	assert.ErrorIs(t, err, ErrNotRunning)
+}
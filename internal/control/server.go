@@ -0,0 +1,164 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Handler answers the requests received by the server
+type Handler interface {
+	ServeControl(Request) Response
+}
+
+// HandlerFunc allows a function to be used as a Handler
+type HandlerFunc func(Request) Response
+
+func (f HandlerFunc) ServeControl(req Request) Response {
+	return f(req)
+}
+
+// connectionTimeout bounds how long a client has to send its request
+// and receive the response
+var connectionTimeout = time.Second * 10
+
+// Server accepts connections on a Unix socket and answers each
+// request with the handler
+type Server struct {
+	logger  logrus.FieldLogger
+	handler Handler
+
+	listenerMu sync.Mutex
+	listener   net.Listener
+
+	wg sync.WaitGroup
+}
+
+// NewServer constructs a server that answers requests with handler
+func NewServer(logger logrus.FieldLogger, handler Handler) *Server {
+	return &Server{
+		logger:  logger,
+		handler: handler,
+	}
+}
+
+// Listen creates the socket at path and serves requests in the background
+// until Stop is called. A socket left behind by a daemon that exited
+// uncleanly is replaced, but a socket in use by another daemon is an error
+func (server *Server) Listen(path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			conn.Close()
+			return fmt.Errorf("%s is in use by another saucisson", path)
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	server.listenerMu.Lock()
+	server.listener = listener
+	server.listenerMu.Unlock()
+
+	server.logger.WithField("path", path).Info("Control socket listening")
+
+	server.wg.Add(1)
+	go func() {
+		defer server.wg.Done()
+		server.serve(listener)
+	}()
+
+	return nil
+}
+
+// serve accepts connections until the listener is closed
+func (server *Server) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			server.logger.WithError(err).Error("Control socket failed to accept connection")
+			continue
+		}
+
+		server.wg.Add(1)
+		go func() {
+			defer server.wg.Done()
+			server.handle(conn)
+		}()
+	}
+}
+
+// handle answers the single request sent on conn
+func (server *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(connectionTimeout))
+
+	var req Request
+	var res Response
+
+	err := json.NewDecoder(conn).Decode(&req)
+	if err != nil {
+		res.Error = fmt.Sprintf("invalid request: %s", err.Error())
+	} else {
+		res = server.handler.ServeControl(req)
+	}
+
+	err = json.NewEncoder(conn).Encode(res)
+	if err != nil {
+		server.logger.WithError(err).Error("Control socket failed to write response")
+	}
+}
+
+// Stop closes the socket, removing it, and waits for requests
+// that are being answered to complete
+func (server *Server) Stop(ctx context.Context) error {
+	server.listenerMu.Lock()
+	if server.listener == nil {
+		server.listenerMu.Unlock()
+		return nil
+	}
+	err := server.listener.Close()
+	server.listener = nil
+	server.listenerMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		server.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
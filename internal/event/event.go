@@ -0,0 +1,113 @@
+package event
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+)
+
+// Event describes the occurrence that satisfied a condition. It is created by
+// the watcher of the condition and passed on to the executor of the service.
+//
+// Fields that are specific to a condition type are left empty for events
+// of other types.
+type Event struct {
+	// Service is the name of the service whose condition was satisfied
+	Service string `json:"service"`
+	// Condition is the type of condition that produced the event
+	Condition config.Condition `json:"condition"`
+	// Time is when the event was observed
+	Time time.Time `json:"time"`
+
+	// Path is the file that changed, for file conditions,
+	// or the path of the request, for webhook conditions
+	Path string `json:"path,omitempty"`
+	// OldPath is the path of a file before it was renamed, for file conditions
+	OldPath string `json:"old_path,omitempty"`
+	// Op is the operation applied to the file, for file conditions
+	Op config.Operation `json:"op,omitempty"`
+
+	// Executable is the name of the process, for process conditions
+	Executable string `json:"executable,omitempty"`
+	// PID is the process id, for process conditions.
+	// For a closed process this is the id it last had
+	PID int `json:"pid,omitempty"`
+	// PPID is the parent process id, for process conditions
+	PPID int `json:"ppid,omitempty"`
+	// State is the state the process changed to, for process conditions
+	State config.State `json:"state,omitempty"`
+
+	// Scheduled is the time the cron schedule was due, for cron conditions
+	Scheduled *time.Time `json:"scheduled,omitempty"`
+
+	// Method is the method of the request, for webhook conditions
+	Method string `json:"method,omitempty"`
+	// Headers are the headers of the request, for webhook conditions
+	Headers http.Header `json:"headers,omitempty"`
+	// Query is the query string of the request, for webhook conditions
+	Query url.Values `json:"query,omitempty"`
+	// Body is the body of the request, for webhook conditions
+	Body string `json:"body,omitempty"`
+
+	// Batch is every event, in order, that was combined into this one by
+	// the debounce or threshold of a service. The event is a copy of the last
+	Batch []Event `json:"batch,omitempty"`
+}
+
+// New constructs an event of the provided condition type observed now
+func New(condition config.Condition) Event {
+	return Event{
+		Condition: condition,
+		Time:      time.Now(),
+	}
+}
+
+// EnvPrefix is prepended to the name of every environment variable produced by Env
+const EnvPrefix = "SAUCISSON_"
+
+// Env returns the event as environment variables in the form KEY=value,
+// e.g. SAUCISSON_PATH=/tmp/file.txt. Empty fields are omitted.
+func (ev Event) Env() []string {
+	env := make([]string, 0)
+
+	add := func(key, value string) {
+		if value != "" {
+			env = append(env, EnvPrefix+key+"="+value)
+		}
+	}
+
+	addInt := func(key string, value int) {
+		if value != 0 {
+			add(key, strconv.Itoa(value))
+		}
+	}
+
+	add("SERVICE", ev.Service)
+	add("CONDITION", string(ev.Condition))
+	if !ev.Time.IsZero() {
+		add("TIME", ev.Time.Format(time.RFC3339Nano))
+	}
+
+	add("PATH", ev.Path)
+	add("OLD_PATH", ev.OldPath)
+	add("OP", string(ev.Op))
+
+	add("EXECUTABLE", ev.Executable)
+	addInt("PID", ev.PID)
+	addInt("PPID", ev.PPID)
+	add("STATE", string(ev.State))
+
+	if ev.Scheduled != nil {
+		add("SCHEDULED", ev.Scheduled.Format(time.RFC3339))
+	}
+
+	add("METHOD", ev.Method)
+	add("QUERY", ev.Query.Encode())
+
+	addInt("BATCH_SIZE", len(ev.Batch))
+
+	return env
+}
@@ -0,0 +1,56 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnv(t *testing.T) {
+	ev := Event{
+		Service:   "svc",
+		Condition: config.FileKey,
+		Path:      "/tmp/new.txt",
+		OldPath:   "/tmp/old.txt",
+		Op:        config.Rename,
+	}
+
+	assert.Equal(t, []string{
+		"SAUCISSON_SERVICE=svc",
+		"SAUCISSON_CONDITION=file",
+		"SAUCISSON_PATH=/tmp/new.txt",
+		"SAUCISSON_OLD_PATH=/tmp/old.txt",
+		"SAUCISSON_OP=rename",
+	}, ev.Env())
+}
+
+func TestEnvProcess(t *testing.T) {
+	scheduled := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+	ev := Event{
+		Condition: config.Processkey,
+		PID:       10,
+		PPID:      1,
+		Scheduled: &scheduled,
+	}
+
+	assert.Equal(t, []string{
+		"SAUCISSON_CONDITION=process",
+		"SAUCISSON_PID=10",
+		"SAUCISSON_PPID=1",
+		"SAUCISSON_SCHEDULED=2022-12-01T10:00:00Z",
+	}, ev.Env())
+}
+
+func TestEnvBatch(t *testing.T) {
+	ev := Event{
+		Condition: config.FileKey,
+		Batch:     []Event{{}, {}, {}},
+	}
+
+	assert.Equal(t, []string{
+		"SAUCISSON_CONDITION=file",
+		"SAUCISSON_BATCH_SIZE=3",
+	}, ev.Env())
+}
@@ -0,0 +1,351 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+)
+
+// NewExec creates a new Exec executor with default values set for
+// optional fields in the configuration and all dependencies
+func NewExec(logger logrus.FieldLogger, templates *Templates) *Exec {
+	return &Exec{
+		logger:    logger,
+		templates: templates,
+		Timeout:   5,
+		MaxOutput: DefaultMaxOutput,
+		KillGrace: DefaultKillGrace,
+	}
+}
+
+// Exec runs an executable directly with a list of arguments, no shell is
+// involved so arguments are passed exactly as written.
+//
+// Env is merged into the environment of saucisson, or replaces it if
+// ReplaceEnv is set. The SAUCISSON_* variables of the event are always set,
+// see event.Event.Env. Stdin is written to the process if set, otherwise
+// the event is written as JSON.
+//
+// If User or Group is set, saucisson must be running as root, the process
+// is run as that user and group. Setting only User uses the primary group
+// of the user.
+//
+// The process is run in its own process group. On timeout or cancellation
+// the group is sent SIGTERM, then SIGKILL if it has not exited within
+// KillGrace, see runGroup. Limits restricts the resources of the process
+// and its children, see Limits.
+//
+// At most MaxOutput bytes of output are captured, in total, the rest is
+// discarded. An ExitError holds the end of stderr.
+//
+// Path, Args, Env, Dir and Stdin are rendered as templates, see Templates
+type Exec struct {
+	logger    logrus.FieldLogger
+	templates *Templates
+
+	LogOutput  bool              `yaml:"log"`
+	Path       string            `yaml:"path"`
+	Args       []string          `yaml:"args"`
+	Env        map[string]string `yaml:"env"`
+	ReplaceEnv bool              `yaml:"replace_env"`
+	Dir        string            `yaml:"dir"`
+	Stdin      string            `yaml:"stdin"`
+	User       string            `yaml:"user"`
+	Group      string            `yaml:"group"`
+	Timeout    int               `yaml:"timeout"`
+	KillGrace  time.Duration     `yaml:"kill_grace"`
+	MaxOutput  config.ByteSize   `yaml:"max_output"`
+	Limits     *Limits           `yaml:"limits"`
+}
+
+// ExitError is returned by Exec when the process exits with a non-zero code
+type ExitError struct {
+	Path string
+	Code int
+	// Stderr is the output of the process on stderr
+	Stderr string
+}
+
+func (err *ExitError) Error() string {
+	if err.Stderr == "" {
+		return fmt.Sprintf("%s exited with code %d", err.Path, err.Code)
+	}
+	return fmt.Sprintf("%s exited with code %d: %s", err.Path, err.Code, err.Stderr)
+}
+
+// Validate checks that a path is provided, that the user and group exist
+// and the timeout is usable
+func (ex *Exec) Validate() []error {
+	var errs []error
+
+	if ex.Path == "" {
+		errs = append(errs, config.Required("path"))
+	} else if err := ex.templates.Check("path", ex.Path); err != nil {
+		errs = append(errs, config.Invalid("path", "is not a valid template: %v", err))
+	}
+
+	for i, arg := range ex.Args {
+		if err := ex.templates.Check("args", arg); err != nil {
+			errs = append(errs, config.Invalid("args", "%d is not a valid template: %v", i, err))
+		}
+	}
+
+	for k, v := range ex.Env {
+		if err := ex.templates.Check("env."+k, v); err != nil {
+			errs = append(errs, config.Invalid("env", "%s is not a valid template: %v", k, err))
+		}
+	}
+
+	if err := ex.templates.Check("dir", ex.Dir); err != nil {
+		errs = append(errs, config.Invalid("dir", "is not a valid template: %v", err))
+	}
+
+	if err := ex.templates.Check("stdin", ex.Stdin); err != nil {
+		errs = append(errs, config.Invalid("stdin", "is not a valid template: %v", err))
+	}
+
+	if ex.User != "" {
+		if _, _, err := lookupUser(ex.User); err != nil {
+			errs = append(errs, config.Invalid("user", "%v", err))
+		}
+	}
+
+	if ex.Group != "" {
+		if _, err := lookupGroup(ex.Group); err != nil {
+			errs = append(errs, config.Invalid("group", "%v", err))
+		}
+	}
+
+	if ex.Timeout <= 0 {
+		errs = append(errs, config.Invalid("timeout", "must be a positive number of seconds"))
+	}
+
+	if ex.KillGrace < 0 {
+		errs = append(errs, config.Invalid("kill_grace", "must not be negative"))
+	}
+
+	if ex.MaxOutput <= 0 {
+		errs = append(errs, config.Invalid("max_output", "must be a positive size"))
+	}
+
+	errs = validateLimits(errs, ex.Limits)
+
+	return errs
+}
+
+// Execute runs the executable and waits for it to exit.
+// ctx is used to propagate any cancellation instructions of the process from the caller
+func (ex *Exec) Execute(ctx context.Context, ev event.Event) error {
+	ctx, done := context.WithTimeout(ctx, time.Second*time.Duration(ex.Timeout))
+	defer done()
+
+	path, err := ex.templates.Render("path", ex.Path, ev)
+	if err != nil {
+		return err
+	}
+
+	args := make([]string, len(ex.Args))
+	for i, arg := range ex.Args {
+		args[i], err = ex.templates.Render("args", arg, ev)
+		if err != nil {
+			return err
+		}
+	}
+
+	env, err := ex.templates.RenderMap("env", ex.Env, ev)
+	if err != nil {
+		return err
+	}
+
+	dir, err := ex.templates.Render("dir", ex.Dir, ev)
+	if err != nil {
+		return err
+	}
+
+	stdin, err := ex.stdin(ev)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Env = ex.environ(env, ev)
+
+	err = ex.credential(cmd)
+	if err != nil {
+		return err
+	}
+
+	err = ex.Limits.wrap(cmd)
+	if err != nil {
+		return err
+	}
+
+	//As for Shell, the tail of stderr is kept in full to explain failures
+	limit := newOutputLimit(int(ex.MaxOutput))
+	stdout := &bytes.Buffer{}
+	stderrTail := &tailBuffer{}
+	reportStdout, reportStderr := outputWriters(ctx)
+	cmd.Stdout = &limitedWriter{Writer: io.MultiWriter(stdout, reportStdout), limit: limit}
+	cmd.Stderr = io.MultiWriter(stderrTail, &limitedWriter{Writer: reportStderr, limit: limit})
+
+	logger := ex.logger.
+		WithField("svc", ev.Service).
+		WithField("job", jobID(ctx))
+
+	err = runGroup(ctx, logger, cmd, ex.KillGrace)
+
+	if limit.Exceeded() {
+		logger.
+			WithField("max_output", ex.MaxOutput).
+			Warn("Exec output exceeded max_output, the rest was discarded")
+	}
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrTimeoutExceeded
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		exceeded, possible := ex.Limits.exceeded(err, stderrTail.String())
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			err = &ExitError{
+				Path:   path,
+				Code:   exitErr.ExitCode(),
+				Stderr: stderrTail.String(),
+			}
+		}
+
+		if exceeded != "" {
+			return &LimitError{Limit: exceeded, Possible: possible, Err: err}
+		}
+		return err
+	}
+
+	if ex.LogOutput {
+		logger.
+			WithField("stdout", stdout.String()).
+			WithField("path", path).
+			WithField("args", args).
+			Info("Exec output")
+	}
+
+	return nil
+}
+
+// stdin renders Stdin, falling back to the event as JSON
+func (ex *Exec) stdin(ev event.Event) ([]byte, error) {
+	if ex.Stdin == "" {
+		return json.Marshal(ev)
+	}
+
+	stdin, err := ex.templates.Render("stdin", ex.Stdin, ev)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(stdin), nil
+}
+
+// environ builds the environment of the process from env and the event
+func (ex *Exec) environ(env map[string]string, ev event.Event) []string {
+	var environ []string
+	if !ex.ReplaceEnv {
+		environ = os.Environ()
+	}
+
+	for k, v := range env {
+		environ = append(environ, k+"="+v)
+	}
+
+	//Later entries take precedence, so the event cannot be overridden
+	return append(environ, ev.Env()...)
+}
+
+// credential configures cmd to run as User and Group, if set
+func (ex *Exec) credential(cmd *exec.Cmd) error {
+	if ex.User == "" && ex.Group == "" {
+		return nil
+	}
+
+	uid, gid := uint32(os.Getuid()), uint32(os.Getgid())
+
+	if ex.User != "" {
+		var err error
+		uid, gid, err = lookupUser(ex.User)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ex.Group != "" {
+		var err error
+		gid, err = lookupGroup(ex.Group)
+		if err != nil {
+			return err
+		}
+	}
+
+	return setCredential(cmd, uid, gid)
+}
+
+// lookupUser resolves a user name, or numeric id, to the uid and primary gid of the user
+func lookupUser(name string) (uint32, uint32, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		if _, numeric := strconv.ParseUint(name, 10, 32); numeric == nil {
+			u, err = user.LookupId(name)
+		}
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("user %q does not have a numeric uid", name)
+	}
+
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("user %q does not have a numeric gid", name)
+	}
+
+	return uint32(uid), uint32(gid), nil
+}
+
+// lookupGroup resolves a group name, or numeric id, to the gid of the group
+func lookupGroup(name string) (uint32, error) {
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		if _, numeric := strconv.ParseUint(name, 10, 32); numeric == nil {
+			g, err = user.LookupGroupId(name)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	gid, err := strconv.ParseUint(g.Gid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("group %q does not have a numeric gid", name)
+	}
+
+	return uint32(gid), nil
+}
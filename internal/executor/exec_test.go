@@ -0,0 +1,96 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecArgsNotEscaped(t *testing.T) {
+	ex := NewExec(logrus.New(), nil)
+	ex.Path = "sh"
+	ex.Args = []string{"-c", `test "$1" = '"quoted" arg'`, "sh", `"quoted" arg`}
+
+	assert.NoError(t, ex.Execute(context.Background(), event.Event{}))
+}
+
+func TestExecExitError(t *testing.T) {
+	ex := NewExec(logrus.New(), nil)
+	ex.Path = "sh"
+	ex.Args = []string{"-c", "echo oops >&2; exit 3"}
+
+	err := ex.Execute(context.Background(), event.Event{})
+
+	assert.Equal(t, &ExitError{Path: "sh", Code: 3, Stderr: "oops"}, err)
+	assert.Equal(t, 3, ExitCode(err))
+}
+
+func TestExecMaxOutput(t *testing.T) {
+	ex := NewExec(logrus.New(), nil)
+	ex.Path = "sh"
+	ex.Args = []string{"-c", `echo 12345; yes x | head -c 2000 >&2; echo last >&2; exit 1`}
+	ex.MaxOutput = 10
+
+	report := NewReport(1 << 20)
+	err := ex.Execute(WithReport(context.Background(), report), event.Event{})
+
+	var exitErr *ExitError
+	if assert.ErrorAs(t, err, &exitErr) {
+		assert.LessOrEqual(t, len(exitErr.Stderr), tailSize)
+		assert.True(t, strings.HasSuffix(exitErr.Stderr, "last"))
+	}
+
+	stdout, stderr, _ := report.Output()
+	assert.Equal(t, "12345\n", stdout)
+	assert.Equal(t, "x\nx\n", stderr)
+}
+
+func TestExecReplaceEnv(t *testing.T) {
+	t.Setenv("SAUCISSON_TEST_INHERITED", "yes")
+
+	ex := NewExec(logrus.New(), nil)
+	ex.Path = "sh"
+	ex.Args = []string{"-c", `test -z "$SAUCISSON_TEST_INHERITED" && test "$FOO" = bar && test "$SAUCISSON_SERVICE" = svc`}
+	ex.Env = map[string]string{"FOO": "bar"}
+	ex.ReplaceEnv = true
+
+	assert.NoError(t, ex.Execute(context.Background(), event.Event{Service: "svc"}))
+}
+
+func TestExecMergeEnv(t *testing.T) {
+	t.Setenv("SAUCISSON_TEST_INHERITED", "yes")
+
+	ex := NewExec(logrus.New(), nil)
+	ex.Path = "sh"
+	ex.Args = []string{"-c", `test "$SAUCISSON_TEST_INHERITED" = yes && test "$FOO" = bar`}
+	ex.Env = map[string]string{"FOO": "bar"}
+
+	assert.NoError(t, ex.Execute(context.Background(), event.Event{}))
+}
+
+func TestExecDirAndStdin(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "marker"), nil, 0644))
+
+	ex := NewExec(logrus.New(), nil)
+	ex.Path = "sh"
+	ex.Args = []string{"-c", "test -f marker && grep -q hello"}
+	ex.Dir = dir
+	ex.Stdin = "hello"
+
+	assert.NoError(t, ex.Execute(context.Background(), event.Event{}))
+}
+
+func TestExecValidateUnknownUser(t *testing.T) {
+	ex := NewExec(logrus.New(), nil)
+	ex.Path = "true"
+	ex.User = "saucisson-no-such-user"
+
+	assert.Len(t, ex.Validate(), 1)
+}
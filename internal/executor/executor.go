@@ -3,25 +3,81 @@ package executor
 import (
 	"context"
 	"errors"
+	"fmt"
+
+	"github.com/mickyco94/saucisson/internal/event"
 )
 
 // An executor is an abstractions that represents
-// some parameterless invocation. Executors are run
-// when a Condition is satisfied.
+// some invocation. Executors are run when a Condition
+// is satisfied.
 type Executor interface {
 
 	// Execute will run the wrapped function.
 	// All errors are logged to the Service diagnostics
 	//
-	// Context is used for cancellation of the running Executors
-	Execute(context.Context) error
+	// Context is used for cancellation of the running Executors.
+	// The event describes the occurrence that satisfied the Condition
+	Execute(context.Context, event.Event) error
 }
 
 // ExecutorFunc defines the method signature that all Executors must follow
-type ExecutorFunc func(context.Context) error
+type ExecutorFunc func(context.Context, event.Event) error
+
+// Execute calls f(ctx, ev), this allows ordinary functions to be used as Executors
+func (f ExecutorFunc) Execute(ctx context.Context, ev event.Event) error {
+	return f(ctx, ev)
+}
+
+// IdleCloser is implemented by executors that keep connections open between
+// executions, such as Http
+type IdleCloser interface {
+	CloseIdleConnections()
+}
+
+// CloseIdleConnections closes the idle connections of executor, if it keeps
+// any, once it is no longer used
+func CloseIdleConnections(executor Executor) {
+	if closer, ok := executor.(IdleCloser); ok {
+		closer.CloseIdleConnections()
+	}
+}
 
 // ErrTimeoutExceeded is an err that indicates the configured timeout for the execution
 // has been exceeded.
 // Timeout for executors can be set by setting the "timeout" property
 // in the executor specification. The units are in seconds for this field.
 var ErrTimeoutExceeded = errors.New("Execution timeout exceeded")
+
+// NotRunError is returned for an event that was intentionally not run, such
+// as one skipped by a Gate or held back by a debounce. Reason says why
+type NotRunError struct {
+	Reason string
+}
+
+func (err *NotRunError) Error() string {
+	return fmt.Sprintf("Event was not run: %s", err.Reason)
+}
+
+// PanicError is returned in place of a panic by an executor decorated with Recovered
+type PanicError struct {
+	Value any
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("Executor panicked: %v", err.Value)
+}
+
+// Recovered decorates executor so that a panic is returned as a PanicError,
+// rather than unwinding the worker that runs it
+func Recovered(executor Executor) ExecutorFunc {
+	return func(ctx context.Context, ev event.Event) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = &PanicError{Value: rec}
+			}
+		}()
+
+		return executor.Execute(ctx, ev)
+	}
+}
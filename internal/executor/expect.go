@@ -0,0 +1,175 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/mickyco94/saucisson/internal/config"
+)
+
+// maxExpectBody is the largest response body that Expect can match
+const maxExpectBody = 10 << 20
+
+// Expect is what a response must match for a request to succeed. Status is
+// the accepted status codes, by default any status below 400. Body is a
+// regular expression that the body must match. JSON maps paths, see
+// jsonPath, to the value that the JSON body must have at each path
+type Expect struct {
+	Status []config.StatusRange `yaml:"status"`
+	Body   string               `yaml:"body"`
+	JSON   map[string]any       `yaml:"json"`
+
+	//body is Body compiled, once it is first needed
+	bodyMu sync.Mutex
+	body   *regexp.Regexp
+}
+
+// ExpectError is returned by Http when the body of a response does not match Expect
+type ExpectError struct {
+	Message string
+}
+
+func (err *ExpectError) Error() string {
+	return fmt.Sprintf("HTTP response %s", err.Message)
+}
+
+// Validate checks that the status ranges, body expression and paths can be
+// parsed
+func (expect *Expect) Validate() []error {
+	var errs []error
+
+	for _, status := range expect.Status {
+		if _, _, err := status.Bounds(); err != nil {
+			errs = append(errs, fmt.Errorf("status %v", err))
+		}
+	}
+
+	if _, err := expect.bodyRegexp(); err != nil {
+		errs = append(errs, err)
+	}
+
+	for path := range expect.JSON {
+		if _, err := parseJSONPath(path); err != nil {
+			errs = append(errs, fmt.Errorf("json %v", err))
+		}
+	}
+
+	return errs
+}
+
+// bodyRegexp returns Body compiled. It is compiled when first needed, and
+// again if Body has changed since
+func (expect *Expect) bodyRegexp() (*regexp.Regexp, error) {
+	expect.bodyMu.Lock()
+	defer expect.bodyMu.Unlock()
+
+	if expect.body == nil || expect.body.String() != expect.Body {
+		body, err := regexp.Compile(expect.Body)
+		if err != nil {
+			return nil, fmt.Errorf("body is not a regular expression: %v", err)
+		}
+		expect.body = body
+	}
+
+	return expect.body, nil
+}
+
+// matchStatus reports whether the status code is accepted
+func (expect *Expect) matchStatus(code int) bool {
+	if len(expect.Status) == 0 {
+		return code < 400
+	}
+
+	for _, status := range expect.Status {
+		if status.Contains(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesBody reports whether the body needs to be matched
+func (expect *Expect) matchesBody() bool {
+	return expect.Body != "" || len(expect.JSON) > 0
+}
+
+// matchBody checks the body of a response, truncated is whether
+// the body was larger than maxExpectBody
+func (expect *Expect) matchBody(body []byte, truncated bool) error {
+	if !expect.matchesBody() {
+		return nil
+	}
+
+	if truncated {
+		return &ExpectError{Message: fmt.Sprintf("body is larger than %d bytes and cannot be matched", maxExpectBody)}
+	}
+
+	if expect.Body != "" {
+		pattern, err := expect.bodyRegexp()
+		if err != nil {
+			return err
+		}
+
+		if !pattern.Match(body) {
+			return &ExpectError{Message: fmt.Sprintf("body does not match %q", expect.Body)}
+		}
+	}
+
+	if len(expect.JSON) == 0 {
+		return nil
+	}
+
+	var document any
+	if err := json.Unmarshal(body, &document); err != nil {
+		return &ExpectError{Message: fmt.Sprintf("body is not JSON: %v", err)}
+	}
+
+	//Sorted so that the first mismatch is reported consistently
+	paths := make([]string, 0, len(expect.JSON))
+	for path := range expect.JSON {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		parsed, err := parseJSONPath(path)
+		if err != nil {
+			return err
+		}
+
+		actual, ok := parsed.lookup(document)
+		if !ok {
+			return &ExpectError{Message: fmt.Sprintf("has no value at %s", path)}
+		}
+
+		expected, err := normaliseJSON(expect.JSON[path])
+		if err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			actualJSON, _ := json.Marshal(actual)
+			expectedJSON, _ := json.Marshal(expected)
+			return &ExpectError{Message: fmt.Sprintf("%s is %s, expected %s", path, actualJSON, expectedJSON)}
+		}
+	}
+
+	return nil
+}
+
+// normaliseJSON converts a value decoded from YAML into the value that
+// encoding/json would decode, e.g. ints become float64
+func normaliseJSON(value any) (any, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalised any
+	err = json.Unmarshal(encoded, &normalised)
+	return normalised, err
+}
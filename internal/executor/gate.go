@@ -0,0 +1,228 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultQueueDepth is the number of triggers that can wait
+// with the queue policy if max_depth is not configured
+var DefaultQueueDepth = 10
+
+// ErrReplaced is returned for an execution that was replaced by
+// a newer trigger before it started
+var ErrReplaced = errors.New("Execution replaced before it started")
+
+// Gate applies the concurrency policy of a single service to its jobs
+// before they are added to the pool, see config.Concurrency.
+//
+// Queued jobs are held by the gate rather than the pool,
+// so that they do not occupy workers while they wait
+type Gate struct {
+	logger   logrus.FieldLogger
+	pool     *Pool
+	policy   config.ConcurrencyPolicy
+	maxDepth int
+	//limit is the number of executions that can run at once, 0 is unlimited
+	limit int
+
+	mu sync.Mutex
+	//active is the number of executions admitted that have not completed,
+	//current is the most recently admitted
+	active  int
+	current *execution
+	queue   []Job
+	stats   GateStats
+}
+
+// GateStats counts the triggers that a gate did not run
+type GateStats struct {
+	Skipped   uint64
+	Cancelled uint64
+}
+
+// execution is a job that has been admitted by the gate
+type execution struct {
+	//stop is closed to cancel the execution
+	stop    chan struct{}
+	stopped bool
+	//done is closed once the execution has completed
+	done chan struct{}
+}
+
+// NewGate constructs a gate that adds jobs to pool according to cfg
+func NewGate(logger logrus.FieldLogger, pool *Pool, cfg config.Concurrency) *Gate {
+	g := &Gate{
+		logger:   logger,
+		pool:     pool,
+		policy:   cfg.Policy,
+		maxDepth: cfg.MaxDepth,
+		limit:    1,
+	}
+
+	if g.policy == "" || g.policy == config.Allow {
+		g.policy = config.Allow
+		g.limit = cfg.MaxConcurrent
+	}
+
+	if g.maxDepth == 0 {
+		g.maxDepth = DefaultQueueDepth
+	}
+
+	return g
+}
+
+// Stats returns the number of triggers skipped and cancelled so far
+func (g *Gate) Stats() GateStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.stats
+}
+
+// Submit applies the policy to the job, adding it to the pool if it should
+// run now. If wait is false the job is added without blocking, see Pool.TryEnqueue.
+// A job that is queued is not an error, one that is skipped returns a NotRunError
+func (g *Gate) Submit(job Job, wait bool) error {
+	if g.limit == 0 {
+		return g.enqueue(job, wait)
+	}
+
+	g.mu.Lock()
+
+	logger := g.logger.
+		WithField("svc", job.Service).
+		WithField("policy", g.policy)
+
+	var previous *execution
+
+	if g.active >= g.limit {
+		switch g.policy {
+		case config.Skip:
+			g.stats.Skipped++
+			g.mu.Unlock()
+			logger.Info("Previous execution is still running, skipping")
+			return &NotRunError{Reason: "previous execution is still running"}
+		case config.Replace:
+			previous = g.current
+			if !previous.stopped {
+				previous.stopped = true
+				close(previous.stop)
+				g.stats.Cancelled++
+				logger.Info("Cancelling previous execution, replaced by a newer trigger")
+			}
+		default:
+			if len(g.queue) >= g.maxDepth {
+				g.stats.Skipped++
+				g.mu.Unlock()
+				logger.WithField("depth", len(g.queue)).Warn("Queue is full, skipping")
+				return &NotRunError{Reason: "concurrency queue is full"}
+			}
+			g.queue = append(g.queue, job)
+			g.mu.Unlock()
+			logger.WithField("depth", len(g.queue)).Debug("Execution limit reached, queued")
+			return nil
+		}
+	}
+
+	exec := g.admit()
+	g.mu.Unlock()
+
+	err := g.enqueue(g.wrap(job, exec, previous), wait)
+	if err != nil {
+		g.finish(exec)
+	}
+	return err
+}
+
+// admit records a new execution as active. Must be called with mu held
+func (g *Gate) admit() *execution {
+	exec := &execution{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	g.active++
+	g.current = exec
+	return exec
+}
+
+// enqueue adds the job to the pool
+func (g *Gate) enqueue(job Job, wait bool) error {
+	if !wait {
+		return g.pool.TryEnqueue(job)
+	}
+
+	return g.pool.Enqueue(job)
+}
+
+// wrap decorates the executor of job so that it is cancelled if exec is
+// stopped, and the gate is notified when it completes or is dropped. The job does not
+// start until previous, if any, has completed
+func (g *Gate) wrap(job Job, exec *execution, previous *execution) Job {
+	executor := job.Executor
+
+	job.Dropped = func() { g.finish(exec) }
+	job.Executor = func(ctx context.Context, ev event.Event) error {
+		defer g.finish(exec)
+
+		if previous != nil {
+			<-previous.done
+		}
+
+		select {
+		case <-exec.stop:
+			return ErrReplaced
+		default:
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		go func() {
+			select {
+			case <-exec.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		return executor(ctx, ev)
+	}
+
+	return job
+}
+
+// finish marks exec as completed, starting the next queued job if any
+func (g *Gate) finish(exec *execution) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	close(exec.done)
+
+	g.active--
+	if g.current == exec {
+		g.current = nil
+	}
+
+	if len(g.queue) == 0 || g.active >= g.limit {
+		return
+	}
+
+	job := g.queue[0]
+	g.queue = g.queue[1:]
+
+	next := g.admit()
+
+	//The worker running exec may be the only worker, so must not wait for one
+	go func() {
+		err := g.pool.Enqueue(g.wrap(job, next, nil))
+		if err != nil {
+			g.finish(next)
+		}
+	}()
+}
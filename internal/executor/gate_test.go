@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingJob constructs a job that records its index once released
+func blockingJob(release chan struct{}, mu *sync.Mutex, ran *[]int, i int) Job {
+	return Job{
+		Service: "svc",
+		Executor: func(ctx context.Context, ev event.Event) error {
+			<-release
+			mu.Lock()
+			*ran = append(*ran, i)
+			mu.Unlock()
+			return nil
+		},
+	}
+}
+
+func TestGateSkip(t *testing.T) {
+	pool := NewPool(logrus.New(), t.Name(), 2)
+	pool.Start()
+	defer pool.Stop(context.Background())
+
+	gate := NewGate(logrus.New(), pool, config.Concurrency{Policy: config.Skip})
+
+	release := make(chan struct{})
+	mu := &sync.Mutex{}
+	ran := []int{}
+
+	var notRun *NotRunError
+	assert.NoError(t, gate.Submit(blockingJob(release, mu, &ran, 1), true))
+	assert.ErrorAs(t, gate.Submit(blockingJob(release, mu, &ran, 2), true), &notRun)
+
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(ran) == 1
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, GateStats{Skipped: 1}, gate.Stats())
+
+	//Once the running execution completes triggers are run again
+	assert.Eventually(t, func() bool {
+		gate.mu.Lock()
+		defer gate.mu.Unlock()
+		return gate.active == 0
+	}, time.Second, time.Millisecond)
+
+	assert.NoError(t, gate.Submit(blockingJob(release, mu, &ran, 3), true))
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(ran) == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestGateQueue(t *testing.T) {
+	//A single worker ensures queued jobs do not wait for an idle worker
+	pool := NewPool(logrus.New(), t.Name(), 1)
+	pool.Start()
+	defer pool.Stop(context.Background())
+
+	gate := NewGate(logrus.New(), pool, config.Concurrency{Policy: config.Queue, MaxDepth: 2})
+
+	release := make(chan struct{})
+	mu := &sync.Mutex{}
+	ran := []int{}
+
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, gate.Submit(blockingJob(release, mu, &ran, i), true))
+	}
+
+	var notRun *NotRunError
+	assert.ErrorAs(t, gate.Submit(blockingJob(release, mu, &ran, 4), true), &notRun)
+
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(ran) == 3
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, []int{1, 2, 3}, ran)
+	assert.Equal(t, GateStats{Skipped: 1}, gate.Stats())
+}
+
+func TestGateReplace(t *testing.T) {
+	pool := NewPool(logrus.New(), t.Name(), 2)
+	pool.Start()
+	defer pool.Stop(context.Background())
+
+	gate := NewGate(logrus.New(), pool, config.Concurrency{Policy: config.Replace})
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+
+	assert.NoError(t, gate.Submit(Job{
+		Service: "svc",
+		Executor: func(ctx context.Context, ev event.Event) error {
+			close(started)
+			<-ctx.Done()
+			close(cancelled)
+			return ctx.Err()
+		},
+	}, true))
+
+	<-started
+
+	replaced := make(chan struct{})
+	assert.NoError(t, gate.Submit(Job{
+		Service: "svc",
+		Executor: func(ctx context.Context, ev event.Event) error {
+			close(replaced)
+			return nil
+		},
+	}, true))
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("running execution was not cancelled")
+	}
+
+	select {
+	case <-replaced:
+	case <-time.After(time.Second):
+		t.Fatal("replacement was not run")
+	}
+
+	assert.Equal(t, GateStats{Cancelled: 1}, gate.Stats())
+}
+
+func TestGateMaxConcurrent(t *testing.T) {
+	pool := NewPool(logrus.New(), t.Name(), 4)
+	pool.Start()
+	defer pool.Stop(context.Background())
+
+	gate := NewGate(logrus.New(), pool, config.Concurrency{MaxConcurrent: 2})
+
+	release := make(chan struct{})
+	mu := &sync.Mutex{}
+	ran := []int{}
+
+	for i := 1; i <= 4; i++ {
+		assert.NoError(t, gate.Submit(blockingJob(release, mu, &ran, i), true))
+	}
+
+	assert.Eventually(t, func() bool { return pool.Stats().Busy == 2 }, time.Second, time.Millisecond)
+
+	//The waiting executions are held by the gate, not the pool
+	assert.Equal(t, 0, pool.Stats().Queued)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 2, pool.Stats().Busy)
+
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(ran) == 4
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, GateStats{}, gate.Stats())
+}
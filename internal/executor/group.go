@@ -0,0 +1,204 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultKillGrace is how long a command has to exit after SIGTERM, unless configured otherwise
+var DefaultKillGrace = 5 * time.Second
+
+// outputWaitDelay is how long the output of a command is read after SIGKILL,
+// processes that left its group can hold the output open indefinitely
+var outputWaitDelay = 5 * time.Second
+
+// Termination is how a command came to exit
+type Termination string
+
+const (
+	// Exited commands exited on their own
+	Exited Termination = "exited"
+	// Terminated commands exited after SIGTERM, within the grace period
+	Terminated Termination = "terminated"
+	// Killed commands were sent SIGKILL after the grace period
+	Killed Termination = "killed"
+)
+
+// severity orders terminations, so that a pipeline reports its worst
+func (termination Termination) severity() int {
+	switch termination {
+	case Exited:
+		return 1
+	case Terminated:
+		return 2
+	case Killed:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// runGroup starts cmd in its own process group and waits for it to exit.
+// If ctx is done first, SIGTERM is sent to the group, followed by SIGKILL if
+// the command has not exited within grace. Unlike exec.CommandContext, this
+// stops the children of the command too, such as those of a shell, which
+// would otherwise hold the output of the command open.
+//
+// How the command exited is added to the report of ctx
+func runGroup(ctx context.Context, logger logrus.FieldLogger, cmd *exec.Cmd, grace time.Duration) error {
+	setProcessGroup(cmd)
+
+	output, err := pipeOutput(cmd)
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		output.close()
+		return err
+	}
+	output.start()
+
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		output.wait()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		reportTermination(ctx, Exited)
+		return err
+	case <-ctx.Done():
+	}
+
+	pid := cmd.Process.Pid
+	logger = logger.WithField("pid", pid)
+
+	//The group is gone if every process has already exited
+	err = signalGroup(pid, syscall.SIGTERM)
+	if err != nil && err != syscall.ESRCH {
+		logger.WithError(err).Warn("Failed to send SIGTERM to process group")
+	}
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		reportTermination(ctx, Terminated)
+		logger.Debug("Process group terminated")
+		return err
+	case <-timer.C:
+	}
+
+	err = signalGroup(pid, syscall.SIGKILL)
+	if err != nil && err != syscall.ESRCH {
+		logger.WithError(err).Warn("Failed to send SIGKILL to process group")
+	}
+
+	reportTermination(ctx, Killed)
+	logger.
+		WithField("kill_grace", grace).
+		Warn("Process group did not exit within kill_grace after SIGTERM, killed")
+
+	timer.Reset(outputWaitDelay)
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+	}
+
+	//Closing the pipes ends the copies, the process itself is gone
+	logger.Warn("Output of the process group is held open by another process, discarding the rest")
+	output.close()
+
+	return <-done
+}
+
+// outputPipes copies the output of a command through pipes that saucisson
+// holds the read ends of, rather than those of exec.Cmd, so that reading
+// can be stopped while processes that left the group of the command still
+// hold the write ends open
+type outputPipes struct {
+	readers []*os.File
+	writers []*os.File
+	targets []io.Writer
+	copies  sync.WaitGroup
+}
+
+// pipeOutput replaces the stdout and stderr of cmd with pipes, unless they
+// are files already
+func pipeOutput(cmd *exec.Cmd) (*outputPipes, error) {
+	output := &outputPipes{}
+
+	stdout, err := output.pipe(cmd.Stdout)
+	if err != nil {
+		output.close()
+		return nil, err
+	}
+
+	stderr, err := output.pipe(cmd.Stderr)
+	if err != nil {
+		output.close()
+		return nil, err
+	}
+
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	return output, nil
+}
+
+// pipe returns the writer that is passed to the command in place of target
+func (output *outputPipes) pipe(target io.Writer) (io.Writer, error) {
+	if _, ok := target.(*os.File); ok || target == nil {
+		return target, nil
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	output.readers = append(output.readers, reader)
+	output.writers = append(output.writers, writer)
+	output.targets = append(output.targets, target)
+	return writer, nil
+}
+
+// start copies each pipe to its target. The write ends are closed, as the
+// command holds its own
+func (output *outputPipes) start() {
+	for i, reader := range output.readers {
+		output.writers[i].Close()
+
+		output.copies.Add(1)
+		go func(reader *os.File, target io.Writer) {
+			defer output.copies.Done()
+			defer reader.Close()
+			io.Copy(target, reader)
+		}(reader, output.targets[i])
+	}
+}
+
+// wait waits for the output to be copied
+func (output *outputPipes) wait() {
+	output.copies.Wait()
+}
+
+// close closes every pipe, ending the copies
+func (output *outputPipes) close() {
+	for i, reader := range output.readers {
+		reader.Close()
+		output.writers[i].Close()
+	}
+}
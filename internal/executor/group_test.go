@@ -0,0 +1,115 @@
+//go:build unix
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// runGroupFor runs command in sh until it exits or timeout passes,
+// returning how it exited
+func runGroupFor(t *testing.T, command string, timeout time.Duration, grace time.Duration) Termination {
+	t.Helper()
+
+	report := NewReport(0)
+	ctx, cancel := context.WithTimeout(WithReport(context.Background(), report), timeout)
+	defer cancel()
+
+	runGroup(ctx, logrus.New(), exec.Command("sh", "-c", command), grace)
+	return report.Termination()
+}
+
+func TestRunGroupExited(t *testing.T) {
+	assert.Equal(t, Exited, runGroupFor(t, "exit 1", time.Second, time.Second))
+}
+
+func TestRunGroupTerminated(t *testing.T) {
+	assert.Equal(t, Terminated, runGroupFor(t, "sleep 30", 100*time.Millisecond, time.Second))
+}
+
+func TestRunGroupKilled(t *testing.T) {
+	started := time.Now()
+
+	//Ignored signals are inherited, so sleep ignores SIGTERM too
+	termination := runGroupFor(t, "trap '' TERM; sleep 30", 100*time.Millisecond, 100*time.Millisecond)
+
+	assert.Equal(t, Killed, termination)
+	assert.Less(t, time.Since(started), 5*time.Second)
+}
+
+func TestRunGroupOutputHeldOpen(t *testing.T) {
+	delay := outputWaitDelay
+	outputWaitDelay = 100 * time.Millisecond
+	defer func() { outputWaitDelay = delay }()
+
+	pidFile := filepath.Join(t.TempDir(), "pid")
+
+	//The new session leaves the group, so it keeps stdout open after SIGKILL
+	cmd := exec.Command("sh", "-c", "setsid sh -c 'echo $$ > "+pidFile+"; exec sleep 30' & trap '' TERM; echo started; sleep 30")
+	stdout := &bytes.Buffer{}
+	cmd.Stdout = stdout
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	runGroup(ctx, logrus.New(), cmd, 100*time.Millisecond)
+
+	assert.Less(t, time.Since(started), 5*time.Second)
+	assert.Equal(t, "started\n", stdout.String())
+
+	content, err := os.ReadFile(pidFile)
+	if assert.NoError(t, err) {
+		pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		assert.NoError(t, err)
+		syscall.Kill(pid, syscall.SIGKILL)
+	}
+}
+
+func TestShellTimeoutStopsChildren(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+
+	shell := NewShell(logrus.New(), nil)
+	shell.Shell = "sh"
+	shell.Timeout = 1
+	//The grandchild holds stdout open, which would block until it exits
+	shell.Command = "sh -c 'echo $$ > " + pidFile + "; sleep 30' & wait"
+
+	started := time.Now()
+	err := shell.Execute(context.Background(), event.Event{})
+
+	assert.ErrorIs(t, err, ErrTimeoutExceeded)
+	assert.Less(t, time.Since(started), 5*time.Second)
+
+	content, err := os.ReadFile(pidFile)
+	assert.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return exited(pid) }, time.Second, 10*time.Millisecond)
+}
+
+// exited reports whether the process has exited, including zombies
+// that have not been reaped, e.g. in a container without an init
+func exited(pid int) bool {
+	if syscall.Kill(pid, 0) == syscall.ESRCH {
+		return true
+	}
+
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	return err == nil && strings.Contains(string(stat), ") Z")
+}
@@ -1,39 +1,167 @@
 package executor
 
 import (
-	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"net/http"
+	"fmt"
+	"io"
+	"mime"
 	nethttp "net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
 	"github.com/sirupsen/logrus"
 )
 
 // Http is an implementation of Executor that makes HTTP Requests.
+// A response with a 4xx or 5xx status is an error, see StatusError, unless
+// Expect accepts other statuses. Expect can also require the body to match.
+//
+// The body of the response is always read in full, but only held in memory
+// when Expect matches it or it is logged. It is written to the file SaveTo,
+// if set, once the response has matched Expect. With log set the body is
+// logged according to its content type, see logResponse.
+//
+// Requests are authenticated by Auth, and made by a client of the executor
+// that is configured by TLS, Proxy and Redirects. Proxy is the URL of a
+// proxy, or direct to disable the proxy of HTTP_PROXY and HTTPS_PROXY.
+//
+// The body of the request is one of Body, the file BodyFile, the url-encoded
+// Form, Multipart or JSON. Files are streamed rather than read into memory.
+//
+// URL, Headers, SaveTo and the fields of the body are rendered as templates,
+// see Templates
 type Http struct {
-	logger logrus.FieldLogger
-	client nethttp.Client
+	logger    logrus.FieldLogger
+	templates *Templates
+
+	//client is constructed by the first execution that succeeds in doing so
+	clientMu sync.Mutex
+	client   *nethttp.Client
 
 	LogResponse bool `yaml:"log"`
 
-	Body    string            `yaml:"body"`
+	Body      string            `yaml:"body"`
+	BodyFile  string            `yaml:"body_file"`
+	Form      map[string]string `yaml:"form"`
+	Multipart *Multipart        `yaml:"multipart"`
+	JSON      map[string]any    `yaml:"json"`
+
 	Method  string            `yaml:"method"`
 	Headers map[string]string `yaml:"headers"`
 	URL     string            `yaml:"url"`
 	Timeout int               `yaml:"timeout"`
+	SaveTo  string            `yaml:"save_to"`
+	Expect  Expect            `yaml:"expect"`
+
+	Auth      Auth      `yaml:"auth"`
+	TLS       TLS       `yaml:"tls"`
+	Proxy     string    `yaml:"proxy"`
+	Redirects Redirects `yaml:"redirects"`
+}
+
+// StatusError is returned by Http when the status of the response is not
+// accepted, by default a 4xx or 5xx status
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("HTTP request failed with status %s", err.Status)
 }
 
 // NewHttp constructs an HTTP struct with only its dependencies and defaults
-// provided. Binding to configuration is done elsewhere in the struct lifecycle.
-func NewHttp(logger logrus.FieldLogger, client http.Client) *Http {
+// provided. Binding to configuration is done elsewhere in the struct lifecycle,
+// the client is constructed by the first execution, see getClient.
+func NewHttp(logger logrus.FieldLogger, templates *Templates) *Http {
 	return &Http{
-		logger:  logger,
-		client:  client,
-		Timeout: 30,
+		logger:    logger,
+		templates: templates,
+		Timeout:   30,
+	}
+}
+
+// validMethods are the request methods accepted by the Http executor,
+// an empty method is treated as GET by net/http
+var validMethods = map[string]struct{}{
+	"":                    {},
+	nethttp.MethodGet:     {},
+	nethttp.MethodHead:    {},
+	nethttp.MethodPost:    {},
+	nethttp.MethodPut:     {},
+	nethttp.MethodPatch:   {},
+	nethttp.MethodDelete:  {},
+	nethttp.MethodConnect: {},
+	nethttp.MethodOptions: {},
+	nethttp.MethodTrace:   {},
+}
+
+// Validate checks that the URL is absolute, the method is known, the timeout
+// is usable and the certificates of TLS can be loaded
+func (http *Http) Validate() []error {
+	var errs []error
+
+	if http.URL == "" {
+		errs = append(errs, config.Required("url"))
+	} else if err := http.templates.Check("url", http.URL); err != nil {
+		errs = append(errs, config.Invalid("url", "is not a valid template: %v", err))
+	} else if u, err := url.Parse(http.URL); !strings.Contains(http.URL, "{{") && (err != nil || u.Scheme == "" || u.Host == "") {
+		errs = append(errs, config.Invalid("url", "%q is not an absolute URL", http.URL))
+	}
+
+	for k, v := range http.Headers {
+		if err := http.templates.Check("headers."+k, v); err != nil {
+			errs = append(errs, config.Invalid("headers", "%s is not a valid template: %v", k, err))
+		}
+	}
+
+	errs = append(errs, http.validateBody()...)
+
+	if err := http.templates.Check("save_to", http.SaveTo); err != nil {
+		errs = append(errs, config.Invalid("save_to", "is not a valid template: %v", err))
+	}
+
+	for _, err := range http.Expect.Validate() {
+		errs = append(errs, config.Invalid("expect", "%s", err.Error()))
+	}
+
+	for _, err := range http.Auth.Validate() {
+		errs = append(errs, config.Invalid("auth", "%s", err.Error()))
+	}
+
+	for _, err := range http.TLS.Validate() {
+		errs = append(errs, config.Invalid("tls", "%s", err.Error()))
+	}
+
+	for _, err := range http.Redirects.Validate() {
+		errs = append(errs, config.Invalid("redirects", "%s", err.Error()))
+	}
+
+	if http.Proxy != "" && http.Proxy != DirectProxy {
+		if u, err := url.Parse(http.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, config.Invalid("proxy", "%q is not an absolute URL, or direct", http.Proxy))
+		}
+	}
+
+	if _, ok := validMethods[http.Method]; !ok {
+		errs = append(errs, config.Invalid("method", "%q is not a HTTP method", http.Method))
+	}
+
+	if http.Timeout <= 0 {
+		errs = append(errs, config.Invalid("timeout", "must be a positive number of seconds"))
 	}
+
+	return errs
 }
 
 // Execute complete a HTTP Request with parameters defined by the Http struct on which the
@@ -42,23 +170,63 @@ func NewHttp(logger logrus.FieldLogger, client http.Client) *Http {
 // HTTP Client making the request.
 // An additional timeout constraint is placed over the HTTP Request context, the length of this
 // timeout is driven by the configuration defined in Http
-func (http *Http) Execute(ctx context.Context) error {
+func (http *Http) Execute(ctx context.Context, ev event.Event) error {
 	ctx, cancel := context.WithTimeout(ctx, time.Duration(http.Timeout)*time.Second)
 	defer cancel()
 
-	buffer := bytes.NewBufferString(http.Body)
+	requestURL, err := http.templates.Render("url", http.URL, ev)
+	if err != nil {
+		return err
+	}
 
-	request, err := nethttp.NewRequestWithContext(ctx, http.Method, http.URL, buffer)
+	headers, err := http.templates.RenderMap("headers", http.Headers, ev)
+	if err != nil {
+		return err
+	}
 
-	for k, v := range http.Headers {
+	saveTo, err := http.templates.Render("save_to", http.SaveTo, ev)
+	if err != nil {
+		return err
+	}
+
+	body, err := http.newBody(ev)
+	if err != nil {
+		return err
+	}
+	//The transport closes the body once sent, unless the request is never made
+	defer body.Close()
+
+	request, err := nethttp.NewRequestWithContext(ctx, http.Method, requestURL, body)
+
+	if err != nil {
+		return err
+	}
+
+	request.ContentLength = body.length
+	request.GetBody = body.getBody
+	if body.length == 0 {
+		request.Body = nethttp.NoBody
+	}
+
+	for k, v := range headers {
 		request.Header.Add(k, v)
 	}
 
+	if body.contentType != "" && request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", body.contentType)
+	}
+
+	err = http.Auth.apply(request)
+	if err != nil {
+		return err
+	}
+
+	client, err := http.getClient()
 	if err != nil {
 		return err
 	}
 
-	response, err := http.client.Do(request)
+	response, err := client.Do(request)
 
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
@@ -68,21 +236,125 @@ func (http *Http) Execute(ctx context.Context) error {
 		}
 	}
 
+	defer response.Body.Close()
+
+	reportStatus(ctx, response.StatusCode)
+	reportRequest(ctx, "%s %s: %s", request.Method, requestURL, response.Status)
+
+	responseBody, size, temp, err := http.readBody(ctx, response, saveTo)
+	if temp != "" {
+		//Unless it has been renamed to save_to
+		defer os.Remove(temp)
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return ErrTimeoutExceeded
+		}
+		return err
+	}
+
 	if http.LogResponse {
+		http.logResponse(ctx, ev, response, responseBody, size)
+	}
 
-		reader := json.NewDecoder(response.Body)
+	if !http.Expect.matchStatus(response.StatusCode) {
+		return &StatusError{Code: response.StatusCode, Status: response.Status}
+	}
+
+	err = http.Expect.matchBody(responseBody.Bytes(), responseBody.limit.Exceeded())
+	if err != nil {
+		return err
+	}
 
-		var body any
-		err := reader.Decode(&body)
+	if temp != "" {
+		return os.Rename(temp, saveTo)
+	}
+
+	return nil
+}
+
+// readBody reads the whole body of response, so that the connection can be
+// reused. The body is written to the report as stdout and, if saveTo is set,
+// to a temporary file beside saveTo, whose path is returned. The body is
+// returned up to maxExpectBody bytes if it is matched or logged, otherwise
+// it is empty, along with its size
+func (http *Http) readBody(ctx context.Context, response *nethttp.Response, saveTo string) (*limitedBuffer, int64, string, error) {
+	buffered := 0
+	if http.LogResponse || http.Expect.matchesBody() {
+		buffered = maxExpectBody
+	}
+
+	body := &limitedBuffer{limit: newOutputLimit(buffered)}
+	reportStdout, _ := outputWriters(ctx)
+	writers := []io.Writer{body, reportStdout}
+
+	temp := ""
+	if saveTo != "" {
+		err := os.MkdirAll(filepath.Dir(saveTo), 0755)
 		if err != nil {
-			return err
+			return body, 0, "", err
+		}
+
+		file, err := os.CreateTemp(filepath.Dir(saveTo), "."+filepath.Base(saveTo)+".*")
+		if err != nil {
+			return body, 0, "", err
 		}
+		defer file.Close()
 
-		http.logger.
-			WithField("status_code", response.StatusCode).
-			WithField("body", body).
-			Info("HTTP Response")
+		temp = file.Name()
+		writers = append(writers, file)
 	}
 
-	return nil
+	size, err := io.Copy(io.MultiWriter(writers...), response.Body)
+	return body, size, temp, err
+}
+
+// logBodySize is the amount of a text or binary body that is logged
+const logBodySize = 4 << 10
+
+// logResponse logs the status and body of a response. JSON bodies are
+// logged as JSON, text bodies as text and anything else as the start of
+// the body in hex, text and binary bodies are truncated to logBodySize
+func (http *Http) logResponse(ctx context.Context, ev event.Event, response *nethttp.Response, body *limitedBuffer, size int64) {
+	contentType := response.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	logger := http.logger.
+		WithField("svc", ev.Service).
+		WithField("job", jobID(ctx)).
+		WithField("status_code", response.StatusCode).
+		WithField("content_type", contentType).
+		WithField("size", size)
+
+	data := body.Bytes()
+	truncated := body.limit.Exceeded()
+
+	if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+		var value any
+		if !truncated && json.Unmarshal(data, &value) == nil {
+			logger.WithField("body", value).Info("HTTP Response")
+			return
+		}
+	}
+
+	text := strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") ||
+		mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml") ||
+		mediaType == "application/x-www-form-urlencoded" ||
+		(mediaType == "" && utf8.Valid(data))
+
+	if len(data) > logBodySize {
+		data = data[:logBodySize]
+		truncated = true
+	}
+
+	if text {
+		logger = logger.WithField("body", string(data))
+	} else {
+		logger = logger.WithField("body", hex.EncodeToString(data))
+	}
+
+	logger.
+		WithField("truncated", truncated).
+		Info("HTTP Response")
 }
@@ -0,0 +1,185 @@
+package executor
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// respond starts a server that responds to every request with status, contentType and body
+func respond(t *testing.T, status int, contentType string, body string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// newTestHttp constructs an Http executor of url
+func newTestHttp(logger logrus.FieldLogger, url string) *Http {
+	http := NewHttp(logger, nil)
+	http.Method = nethttp.MethodGet
+	http.URL = url
+	return http
+}
+
+func TestHttpStatus(t *testing.T) {
+	server := respond(t, nethttp.StatusNotFound, "text/plain", "missing")
+	http := newTestHttp(logrus.New(), server.URL)
+
+	var statusErr *StatusError
+	assert.ErrorAs(t, http.Execute(context.Background(), event.Event{}), &statusErr)
+
+	http.Expect.Status = []config.StatusRange{"2xx", "404"}
+	assert.NoError(t, http.Execute(context.Background(), event.Event{}))
+
+	http.Expect.Status = []config.StatusRange{"200"}
+	assert.ErrorAs(t, http.Execute(context.Background(), event.Event{}), &statusErr)
+}
+
+func TestHttpExpectBody(t *testing.T) {
+	server := respond(t, nethttp.StatusOK, "application/json", `{"status": "up", "checks": [{"count": 3}]}`)
+	http := newTestHttp(logrus.New(), server.URL)
+
+	http.Expect.Body = `"status":\s*"up"`
+	http.Expect.JSON = map[string]any{"$.status": "up", "checks[0].count": 3}
+	assert.Empty(t, http.Validate())
+	assert.NoError(t, http.Execute(context.Background(), event.Event{}))
+
+	var expectErr *ExpectError
+
+	http.Expect.JSON = map[string]any{"$.status": "down"}
+	err := http.Execute(context.Background(), event.Event{})
+	if assert.ErrorAs(t, err, &expectErr) {
+		assert.Equal(t, `HTTP response $.status is "up", expected "down"`, err.Error())
+	}
+
+	http.Expect.JSON = map[string]any{"$.missing": "up"}
+	assert.ErrorAs(t, http.Execute(context.Background(), event.Event{}), &expectErr)
+
+	http.Expect.JSON = nil
+	http.Expect.Body = "down"
+	assert.Empty(t, http.Validate())
+	assert.ErrorAs(t, http.Execute(context.Background(), event.Event{}), &expectErr)
+}
+
+func TestHttpExpectBodyWithoutValidate(t *testing.T) {
+	server := respond(t, nethttp.StatusOK, "text/plain", "up")
+	http := newTestHttp(logrus.New(), server.URL)
+
+	//The body expression is compiled when first matched
+	http.Expect.Body = "^up$"
+	assert.NoError(t, http.Execute(context.Background(), event.Event{}))
+
+	http.Expect.Body = "down"
+	var expectErr *ExpectError
+	assert.ErrorAs(t, http.Execute(context.Background(), event.Event{}), &expectErr)
+
+	http.Expect.Body = "("
+	assert.EqualError(t, http.Execute(context.Background(), event.Event{}), "body is not a regular expression: error parsing regexp: missing closing ): `(`")
+}
+
+func TestHttpReadBodyBuffered(t *testing.T) {
+	server := respond(t, nethttp.StatusOK, "text/plain", "body")
+	http := newTestHttp(logrus.New(), server.URL)
+
+	read := func() (string, int64) {
+		response, err := nethttp.Get(server.URL)
+		if !assert.NoError(t, err) {
+			return "", 0
+		}
+		defer response.Body.Close()
+
+		body, size, _, err := http.readBody(context.Background(), response, "")
+		assert.NoError(t, err)
+		return body.String(), size
+	}
+
+	//Nothing needs the body, so it is only counted
+	body, size := read()
+	assert.Equal(t, "", body)
+	assert.Equal(t, int64(4), size)
+
+	http.LogResponse = true
+	body, _ = read()
+	assert.Equal(t, "body", body)
+
+	http.LogResponse = false
+	http.Expect.Body = "body"
+	body, _ = read()
+	assert.Equal(t, "body", body)
+}
+
+func TestHttpSaveTo(t *testing.T) {
+	server := respond(t, nethttp.StatusOK, "text/plain", "saved")
+	path := filepath.Join(t.TempDir(), "responses", "latest.txt")
+
+	http := newTestHttp(logrus.New(), server.URL)
+	http.SaveTo = path
+	assert.NoError(t, http.Execute(context.Background(), event.Event{}))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "saved", string(content))
+
+	//A response that does not match is not saved, nor left behind
+	http.Expect.Body = "other"
+	assert.Empty(t, http.Validate())
+	assert.Error(t, http.Execute(context.Background(), event.Event{}))
+
+	content, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "saved", string(content))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestHttpLogResponse(t *testing.T) {
+	cases := []struct {
+		contentType string
+		body        string
+		logged      any
+	}{
+		{"application/json; charset=utf-8", `{"ok": true}`, map[string]any{"ok": true}},
+		{"application/problem+json", `not json`, "not json"},
+		{"text/html", "<p>hello</p>", "<p>hello</p>"},
+		{"application/octet-stream", "\x00\x01", "0001"},
+	}
+
+	for _, c := range cases {
+		logger, hook := test.NewNullLogger()
+		http := newTestHttp(logger, respond(t, nethttp.StatusOK, c.contentType, c.body).URL)
+		http.LogResponse = true
+
+		assert.NoError(t, http.Execute(context.Background(), event.Event{}))
+		assert.Equal(t, c.logged, hook.LastEntry().Data["body"], c.contentType)
+	}
+}
+
+func TestHttpReportsBody(t *testing.T) {
+	server := respond(t, nethttp.StatusInternalServerError, "text/plain", "failed")
+	http := newTestHttp(logrus.New(), server.URL)
+
+	report := NewReport(1024)
+	assert.Error(t, http.Execute(WithReport(context.Background(), report), event.Event{}))
+
+	stdout, _, _ := report.Output()
+	assert.Equal(t, "failed", stdout)
+	assert.Equal(t, nethttp.StatusInternalServerError, report.HttpStatus())
+}
@@ -0,0 +1,338 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+)
+
+// Multipart is a multipart/form-data body of Fields and Files, Files maps
+// the name of each field to the path of the file uploaded as it
+type Multipart struct {
+	Fields map[string]string `yaml:"fields"`
+	Files  map[string]string `yaml:"files"`
+}
+
+// requestBody is a rendered request body, with the Content-Type it is sent
+// with unless the headers set one. Length is -1 when it is not known.
+// getBody returns a new copy of the body, so that it can be sent again when
+// a redirect is followed, it is nil when the body cannot be sent again
+type requestBody struct {
+	io.ReadCloser
+	length      int64
+	contentType string
+	getBody     func() (io.ReadCloser, error)
+}
+
+// bodyModes returns the body fields of http that are set
+func (http *Http) bodyModes() []string {
+	var modes []string
+
+	if http.Body != "" {
+		modes = append(modes, "body")
+	}
+	if http.BodyFile != "" {
+		modes = append(modes, "body_file")
+	}
+	if http.Form != nil {
+		modes = append(modes, "form")
+	}
+	if http.Multipart != nil {
+		modes = append(modes, "multipart")
+	}
+	if http.JSON != nil {
+		modes = append(modes, "json")
+	}
+
+	return modes
+}
+
+// validateBody checks that only one body mode is set and that its templates are valid
+func (http *Http) validateBody() []error {
+	var errs []error
+
+	if modes := http.bodyModes(); len(modes) > 1 {
+		errs = append(errs, config.Invalid(modes[1], "must not be set with %s", modes[0]))
+	}
+
+	if err := http.templates.Check("body", http.Body); err != nil {
+		errs = append(errs, config.Invalid("body", "is not a valid template: %v", err))
+	}
+
+	if err := http.templates.Check("body_file", http.BodyFile); err != nil {
+		errs = append(errs, config.Invalid("body_file", "is not a valid template: %v", err))
+	}
+
+	for k, v := range http.Form {
+		if err := http.templates.Check("form."+k, v); err != nil {
+			errs = append(errs, config.Invalid("form", "%s is not a valid template: %v", k, err))
+		}
+	}
+
+	if http.Multipart != nil {
+		if len(http.Multipart.Fields) == 0 && len(http.Multipart.Files) == 0 {
+			errs = append(errs, config.Invalid("multipart", "must have fields or files"))
+		}
+
+		for k, v := range http.Multipart.Fields {
+			if err := http.templates.Check("multipart.fields."+k, v); err != nil {
+				errs = append(errs, config.Invalid("multipart", "fields.%s is not a valid template: %v", k, err))
+			}
+		}
+
+		for k, v := range http.Multipart.Files {
+			if v == "" {
+				errs = append(errs, config.Invalid("multipart", "files.%s is required", k))
+			} else if err := http.templates.Check("multipart.files."+k, v); err != nil {
+				errs = append(errs, config.Invalid("multipart", "files.%s is not a valid template: %v", k, err))
+			}
+		}
+	}
+
+	if http.JSON != nil {
+		_, err := json.Marshal(http.JSON)
+		if err != nil {
+			errs = append(errs, config.Invalid("json", "cannot be encoded as JSON: %v", err))
+		} else if _, err := http.renderJSON("json", http.JSON, checkEvent()); err != nil {
+			errs = append(errs, config.Invalid("json", "%v", err))
+		}
+	}
+
+	return errs
+}
+
+// newBody renders the body of a request. Files are opened here, so that a
+// missing file is an error before the request is made, and are streamed as
+// the request is sent
+func (http *Http) newBody(ev event.Event) (*requestBody, error) {
+	switch {
+	case http.BodyFile != "":
+		return http.fileBody(ev)
+	case http.Form != nil:
+		return http.formBody(ev)
+	case http.Multipart != nil:
+		return http.multipartBody(ev)
+	case http.JSON != nil:
+		return http.jsonBody(ev)
+	}
+
+	body, err := http.templates.Render("body", http.Body, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	return stringBody(body, ""), nil
+}
+
+// stringBody is a requestBody of s
+func stringBody(s, contentType string) *requestBody {
+	getBody := func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(s)), nil
+	}
+	body, _ := getBody()
+
+	return &requestBody{
+		ReadCloser:  body,
+		length:      int64(len(s)),
+		contentType: contentType,
+		getBody:     getBody,
+	}
+}
+
+// fileBody streams the file body_file, with the Content-Type of its extension.
+// The file is sent up to its size when opened, so that a file that is still
+// being written matches the Content-Length of the request
+func (http *Http) fileBody(ev event.Event) (*requestBody, error) {
+	path, err := http.templates.Render("body_file", http.BodyFile, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	size := info.Size()
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	//Redirects reopen the file, with the length of the first request
+	getBody := func() (io.ReadCloser, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return limitedFile(file, size), nil
+	}
+
+	return &requestBody{
+		ReadCloser:  limitedFile(file, size),
+		length:      size,
+		contentType: contentType,
+		getBody:     getBody,
+	}, nil
+}
+
+// limitedFile reads file up to size bytes, and closes it
+func limitedFile(file *os.File, size int64) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, size), file}
+}
+
+// formBody url-encodes form
+func (http *Http) formBody(ev event.Event) (*requestBody, error) {
+	form, err := http.templates.RenderMap("form", http.Form, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	values := url.Values{}
+	for k, v := range form {
+		values.Set(k, v)
+	}
+
+	return stringBody(values.Encode(), "application/x-www-form-urlencoded"), nil
+}
+
+// jsonBody marshals json, rendering its strings as templates
+func (http *Http) jsonBody(ev event.Event) (*requestBody, error) {
+	value, err := http.renderJSON("json", http.JSON, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return stringBody(string(encoded), "application/json"), nil
+}
+
+// renderJSON renders every string in value as a template, returning a copy
+func (http *Http) renderJSON(name string, value any, ev event.Event) (any, error) {
+	switch value := value.(type) {
+	case string:
+		return http.templates.Render(name, value, ev)
+	case map[string]any:
+		rendered := make(map[string]any, len(value))
+		for k, v := range value {
+			r, err := http.renderJSON(name+"."+k, v, ev)
+			if err != nil {
+				return nil, err
+			}
+			rendered[k] = r
+		}
+		return rendered, nil
+	case []any:
+		rendered := make([]any, len(value))
+		for i, v := range value {
+			r, err := http.renderJSON(fmt.Sprintf("%s[%d]", name, i), v, ev)
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = r
+		}
+		return rendered, nil
+	}
+
+	return value, nil
+}
+
+// multipartBody streams the fields and files of multipart through a pipe,
+// so that files are never held in memory. Its length is not known, so the
+// request is sent chunked
+func (http *Http) multipartBody(ev event.Event) (*requestBody, error) {
+	fields, err := http.templates.RenderMap("multipart.fields", http.Multipart.Fields, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := http.templates.RenderMap("multipart.files", http.Multipart.Files, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	//Parts are written in order of their names, so that the body is reproducible
+	fieldNames := sortedKeys(fields)
+	fileNames := sortedKeys(paths)
+
+	files := make([]*os.File, 0, len(fileNames))
+	closeFiles := func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}
+
+	for _, name := range fileNames {
+		file, err := os.Open(paths[name])
+		if err != nil {
+			closeFiles()
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		defer closeFiles()
+
+		writer.CloseWithError(func() error {
+			for _, name := range fieldNames {
+				err := form.WriteField(name, fields[name])
+				if err != nil {
+					return err
+				}
+			}
+
+			for i, name := range fileNames {
+				part, err := form.CreateFormFile(name, filepath.Base(paths[name]))
+				if err != nil {
+					return err
+				}
+
+				_, err = io.Copy(part, files[i])
+				if err != nil {
+					return err
+				}
+			}
+
+			return form.Close()
+		}())
+	}()
+
+	return &requestBody{ReadCloser: reader, length: -1, contentType: form.FormDataContentType()}, nil
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,191 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// received is a request received by a server of receive
+type received struct {
+	request *nethttp.Request
+	body    []byte
+}
+
+// receive starts a server that records the last request it received, reading
+// the body unless it is a form, which is parsed instead
+func receive(t *testing.T) (*httptest.Server, *received) {
+	t.Helper()
+
+	last := &received{}
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		last.request = r
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "application/x-www-form-urlencoded":
+			assert.NoError(t, r.ParseForm())
+		case "multipart/form-data":
+			assert.NoError(t, r.ParseMultipartForm(1<<20))
+		default:
+			last.body, _ = io.ReadAll(r.Body)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, last
+}
+
+func TestHttpBodyFile(t *testing.T) {
+	server, last := receive(t)
+
+	path := filepath.Join(t.TempDir(), "artifact.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"built": true}`), 0600))
+
+	http := newTestHttp(logrus.New(), server.URL)
+	http.Method = nethttp.MethodPut
+	http.BodyFile = filepath.Join(filepath.Dir(path), "{{ .Event.Path }}")
+	assert.Empty(t, http.Validate())
+
+	assert.NoError(t, http.Execute(context.Background(), event.Event{Path: "artifact.json"}))
+	assert.Equal(t, `{"built": true}`, string(last.body))
+	assert.Equal(t, int64(15), last.request.ContentLength)
+	assert.Equal(t, "application/json", last.request.Header.Get("Content-Type"))
+
+	assert.ErrorIs(t, http.Execute(context.Background(), event.Event{Path: "missing"}), os.ErrNotExist)
+}
+
+func TestHttpBodyFileGrowing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "growing.log")
+	assert.NoError(t, os.WriteFile(path, []byte("first\n"), 0600))
+
+	http := newTestHttp(logrus.New(), "http://example.com")
+	http.BodyFile = path
+	assert.Empty(t, http.Validate())
+
+	body, err := http.newBody(event.Event{})
+	assert.NoError(t, err)
+	defer body.Close()
+
+	//Written after the file was opened, so it is not sent
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	assert.NoError(t, err)
+	file.WriteString("second\n")
+	file.Close()
+
+	content, err := io.ReadAll(body)
+	assert.NoError(t, err)
+	assert.Equal(t, "first\n", string(content))
+	assert.Equal(t, int64(6), body.length)
+}
+
+func TestHttpBodyFileRedirect(t *testing.T) {
+	server, last := receive(t)
+	redirect := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		nethttp.Redirect(w, r, server.URL, nethttp.StatusTemporaryRedirect)
+	}))
+	defer redirect.Close()
+
+	path := filepath.Join(t.TempDir(), "artifact.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("artifact"), 0600))
+
+	http := newTestHttp(logrus.New(), redirect.URL)
+	http.Method = nethttp.MethodPost
+	http.BodyFile = path
+	assert.Empty(t, http.Validate())
+
+	assert.NoError(t, http.Execute(context.Background(), event.Event{}))
+	assert.Equal(t, "artifact", string(last.body))
+	assert.Equal(t, nethttp.MethodPost, last.request.Method)
+}
+
+func TestHttpForm(t *testing.T) {
+	server, last := receive(t)
+
+	http := newTestHttp(logrus.New(), server.URL)
+	http.Method = nethttp.MethodPost
+	http.Form = map[string]string{"service": "{{ .Service }}", "note": "a & b"}
+	assert.Empty(t, http.Validate())
+
+	assert.NoError(t, http.Execute(context.Background(), event.Event{Service: "deploy"}))
+	assert.Equal(t, "deploy", last.request.PostForm.Get("service"))
+	assert.Equal(t, "a & b", last.request.PostForm.Get("note"))
+}
+
+func TestHttpMultipart(t *testing.T) {
+	server, last := receive(t)
+
+	path := filepath.Join(t.TempDir(), "report.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("all good"), 0600))
+
+	http := newTestHttp(logrus.New(), server.URL)
+	http.Method = nethttp.MethodPost
+	http.Multipart = &Multipart{
+		Fields: map[string]string{"service": "{{ .Service }}"},
+		Files:  map[string]string{"report": path},
+	}
+	assert.Empty(t, http.Validate())
+
+	assert.NoError(t, http.Execute(context.Background(), event.Event{Service: "nightly"}))
+	assert.Equal(t, []string{"nightly"}, last.request.MultipartForm.Value["service"])
+
+	files := last.request.MultipartForm.File["report"]
+	if assert.Len(t, files, 1) {
+		assert.Equal(t, "report.txt", files[0].Filename)
+
+		file, err := files[0].Open()
+		assert.NoError(t, err)
+		defer file.Close()
+
+		content, _ := io.ReadAll(file)
+		assert.Equal(t, "all good", string(content))
+	}
+
+	http.Multipart.Files["report"] = filepath.Join(t.TempDir(), "missing.txt")
+	assert.ErrorIs(t, http.Execute(context.Background(), event.Event{}), os.ErrNotExist)
+}
+
+func TestHttpJSON(t *testing.T) {
+	server, last := receive(t)
+
+	http := newTestHttp(logrus.New(), server.URL)
+	http.Method = nethttp.MethodPost
+	http.Headers = map[string]string{"Content-Type": "application/vnd.deploy+json"}
+	http.JSON = map[string]any{
+		"service": "{{ .Service }}",
+		"tags":    []any{"a", "{{ .Service }}"},
+		"retries": 3,
+	}
+	assert.Empty(t, http.Validate())
+
+	assert.NoError(t, http.Execute(context.Background(), event.Event{Service: "deploy"}))
+	assert.Equal(t, "application/vnd.deploy+json", last.request.Header.Get("Content-Type"))
+
+	var body map[string]any
+	assert.NoError(t, json.Unmarshal(last.body, &body))
+	assert.Equal(t, map[string]any{"service": "deploy", "tags": []any{"a", "deploy"}, "retries": 3.0}, body)
+}
+
+func TestHttpBodyValidate(t *testing.T) {
+	http := newTestHttp(logrus.New(), "https://example.com")
+	http.Body = "body"
+	http.Form = map[string]string{"a": "b"}
+	assert.Len(t, http.Validate(), 1)
+
+	http = newTestHttp(logrus.New(), "https://example.com")
+	http.Multipart = &Multipart{}
+	assert.Len(t, http.Validate(), 1)
+
+	http = newTestHttp(logrus.New(), "https://example.com")
+	http.JSON = map[string]any{"nested": map[string]any{"value": "{{ .Vars.missing }}"}}
+	assert.Len(t, http.Validate(), 1)
+}
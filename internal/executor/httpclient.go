@@ -0,0 +1,304 @@
+package executor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Auth authenticates the requests of an Http executor. Username and Password
+// use basic authentication. Token, TokenFile or TokenEnv send a bearer token,
+// a file or environment variable is read for every request so that the token
+// can be rotated
+type Auth struct {
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+	Token     string `yaml:"token"`
+	TokenFile string `yaml:"token_file"`
+	TokenEnv  string `yaml:"token_env"`
+}
+
+// Validate checks that a single method of authentication is configured
+func (auth *Auth) Validate() []error {
+	var errs []error
+
+	tokens := 0
+	for _, source := range []string{auth.Token, auth.TokenFile, auth.TokenEnv} {
+		if source != "" {
+			tokens++
+		}
+	}
+
+	if tokens > 1 {
+		errs = append(errs, fmt.Errorf("only one of token, token_file or token_env can be set"))
+	}
+
+	if auth.Username != "" && tokens > 0 {
+		errs = append(errs, fmt.Errorf("username cannot be used with a token"))
+	}
+
+	if auth.Password != "" && auth.Username == "" {
+		errs = append(errs, fmt.Errorf("password requires a username"))
+	}
+
+	return errs
+}
+
+// apply adds the credentials to request
+func (auth *Auth) apply(request *nethttp.Request) error {
+	if auth.Username != "" {
+		request.SetBasicAuth(auth.Username, auth.Password)
+		return nil
+	}
+
+	token := auth.Token
+
+	if auth.TokenFile != "" {
+		content, err := os.ReadFile(auth.TokenFile)
+		if err != nil {
+			return fmt.Errorf("reading token: %w", err)
+		}
+		token = strings.TrimSpace(string(content))
+	}
+
+	if auth.TokenEnv != "" {
+		value, ok := os.LookupEnv(auth.TokenEnv)
+		if !ok {
+			return fmt.Errorf("reading token: %s is not set", auth.TokenEnv)
+		}
+		token = strings.TrimSpace(value)
+	}
+
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return nil
+}
+
+// TLS configures the TLS connections of an Http executor. CA is the path of
+// a PEM bundle of certificates that are trusted in addition to those of the
+// system. Cert and Key are the paths of a PEM client certificate and its key.
+// MinVersion is 1.0, 1.1, 1.2 or 1.3, defaulting to that of Go
+type TLS struct {
+	CA                 string `yaml:"ca"`
+	Cert               string `yaml:"cert"`
+	Key                string `yaml:"key"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	MinVersion         string `yaml:"min_version"`
+}
+
+// tlsVersions are the accepted values of TLS.MinVersion
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Validate checks that the min version is known and that the certificates
+// can be loaded
+func (t *TLS) Validate() []error {
+	var errs []error
+
+	if _, ok := tlsVersions[t.MinVersion]; t.MinVersion != "" && !ok {
+		errs = append(errs, fmt.Errorf("min_version %q is not one of 1.0, 1.1, 1.2 or 1.3", t.MinVersion))
+	}
+
+	if t.CA != "" {
+		if _, err := t.rootCAs(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if (t.Cert == "") != (t.Key == "") {
+		errs = append(errs, errors.New("cert and key must be set together"))
+	} else if t.Cert != "" {
+		if _, err := t.certificate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// config loads the certificates and constructs the TLS config
+func (t *TLS) config() (*tls.Config, error) {
+	if errs := t.Validate(); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	config := &tls.Config{
+		InsecureSkipVerify: t.InsecureSkipVerify,
+		MinVersion:         tlsVersions[t.MinVersion],
+	}
+
+	if t.CA != "" {
+		pool, err := t.rootCAs()
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if t.Cert != "" {
+		certificate, err := t.certificate()
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// rootCAs loads CA, adding its certificates to those of the system
+func (t *TLS) rootCAs() (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	pem, err := os.ReadFile(t.CA)
+	if err != nil {
+		return nil, fmt.Errorf("ca %v", err)
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("ca %s has no PEM certificates", t.CA)
+	}
+
+	return pool, nil
+}
+
+// certificate loads the client certificate of Cert and Key
+func (t *TLS) certificate() (tls.Certificate, error) {
+	certificate, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+	if err != nil {
+		return certificate, fmt.Errorf("cert %v", err)
+	}
+	return certificate, nil
+}
+
+// Redirect policies of Redirects.Policy
+const (
+	FollowRedirects   = "follow"
+	SameHostRedirects = "same_host"
+	NoRedirects       = "none"
+)
+
+// defaultMaxRedirects is the number of redirects followed by default, as net/http
+const defaultMaxRedirects = 10
+
+// Redirects determines which redirects an Http executor follows. Policy is
+// follow, the default, same_host, which only follows redirects to the host
+// of the request, or none. If a redirect is not followed the redirect is the
+// response. At most Max redirects are followed, by default 10
+type Redirects struct {
+	Policy string `yaml:"policy"`
+	Max    int    `yaml:"max"`
+}
+
+// Validate checks that the policy is known and the maximum is usable
+func (redirects *Redirects) Validate() []error {
+	var errs []error
+
+	switch redirects.Policy {
+	case "", FollowRedirects, SameHostRedirects, NoRedirects:
+	default:
+		errs = append(errs, fmt.Errorf("policy %q is not one of follow, same_host or none", redirects.Policy))
+	}
+
+	if redirects.Max < 0 {
+		errs = append(errs, fmt.Errorf("max must not be negative"))
+	}
+
+	return errs
+}
+
+// check implements http.Client.CheckRedirect
+func (redirects *Redirects) check(request *nethttp.Request, via []*nethttp.Request) error {
+	max := redirects.Max
+	if max == 0 {
+		max = defaultMaxRedirects
+	}
+
+	switch {
+	case redirects.Policy == NoRedirects:
+		return nethttp.ErrUseLastResponse
+	case redirects.Policy == SameHostRedirects && request.URL.Host != via[0].URL.Host:
+		return nethttp.ErrUseLastResponse
+	case len(via) > max:
+		return fmt.Errorf("stopped after %d redirects", max)
+	}
+
+	return nil
+}
+
+// DirectProxy is the value of Http.Proxy that disables proxies
+const DirectProxy = "direct"
+
+// getClient returns the client of the executor, constructing it on first
+// use, once the executor is configured. The client has its own transport,
+// so that connections are reused by the requests of the executor.
+//
+// Only a client that was constructed is kept, so that a CA, cert or key that
+// could not be read, e.g. while it is rotated, is read again by the next request
+func (http *Http) getClient() (*nethttp.Client, error) {
+	http.clientMu.Lock()
+	defer http.clientMu.Unlock()
+
+	if http.client == nil {
+		client, err := http.newClient()
+		if err != nil {
+			return nil, err
+		}
+		http.client = client
+	}
+	return http.client, nil
+}
+
+// CloseIdleConnections closes the idle connections of the client, once the
+// executor is replaced. Requests that are in progress are unaffected
+func (http *Http) CloseIdleConnections() {
+	http.clientMu.Lock()
+	defer http.clientMu.Unlock()
+
+	if http.client != nil {
+		http.client.CloseIdleConnections()
+	}
+}
+
+// newClient constructs a client from the TLS, proxy and redirect config of the executor
+func (http *Http) newClient() (*nethttp.Client, error) {
+	transport := nethttp.DefaultTransport.(*nethttp.Transport).Clone()
+
+	tlsConfig, err := http.TLS.config()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	switch http.Proxy {
+	case "":
+		transport.Proxy = nethttp.ProxyFromEnvironment
+	case DirectProxy:
+		transport.Proxy = nil
+	default:
+		proxy, err := url.Parse(http.Proxy)
+		if err != nil || proxy.Scheme == "" || proxy.Host == "" {
+			return nil, fmt.Errorf("proxy %q is not an absolute URL, or direct", http.Proxy)
+		}
+		transport.Proxy = nethttp.ProxyURL(proxy)
+	}
+
+	redirects := http.Redirects
+	return &nethttp.Client{
+		Transport:     transport,
+		CheckRedirect: redirects.check,
+	}, nil
+}
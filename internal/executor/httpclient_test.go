@@ -0,0 +1,218 @@
+package executor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// validated constructs an Http executor of url, checking that it is valid
+func validated(t *testing.T, url string, configure func(http *Http)) *Http {
+	t.Helper()
+
+	http := newTestHttp(logrus.New(), url)
+	configure(http)
+	assert.Empty(t, http.Validate())
+	return http
+}
+
+func TestHttpAuth(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("from-file\n"), 0600))
+	t.Setenv("SAUCISSON_TEST_TOKEN", "from-env")
+
+	cases := map[string]Auth{
+		"Basic dXNlcjpwYXNz": {Username: "user", Password: "pass"},
+		"Bearer secret":      {Token: "secret"},
+		"Bearer from-file":   {TokenFile: tokenFile},
+		"Bearer from-env":    {TokenEnv: "SAUCISSON_TEST_TOKEN"},
+	}
+
+	for expected, auth := range cases {
+		http := validated(t, server.URL, func(http *Http) { http.Auth = auth })
+		assert.NoError(t, http.Execute(context.Background(), event.Event{}))
+		assert.Equal(t, expected, authorization)
+	}
+}
+
+func TestHttpAuthValidate(t *testing.T) {
+	assert.Len(t, (&Auth{Token: "a", TokenEnv: "B"}).Validate(), 1)
+	assert.Len(t, (&Auth{Username: "user", Token: "a"}).Validate(), 1)
+	assert.Len(t, (&Auth{Password: "pass"}).Validate(), 1)
+}
+
+// writePEM writes the certificate and key of server as PEM files, returning their paths
+func writePEM(t *testing.T, server *httptest.Server) (string, string) {
+	t.Helper()
+
+	certificate := server.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(certificate.PrivateKey)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})
+	assert.NoError(t, os.WriteFile(certPath, certPEM, 0600))
+	assert.NoError(t, os.WriteFile(keyPath, keyPEM, 0600))
+
+	return certPath, keyPath
+}
+
+func TestHttpTLS(t *testing.T) {
+	var clientCertificates int
+	server := httptest.NewUnstartedServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		clientCertificates = len(r.TLS.PeerCertificates)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	certPath, keyPath := writePEM(t, server)
+
+	untrusted := validated(t, server.URL, func(http *Http) {})
+	assert.Error(t, untrusted.Execute(context.Background(), event.Event{}))
+
+	insecure := validated(t, server.URL, func(http *Http) { http.TLS.InsecureSkipVerify = true })
+	assert.NoError(t, insecure.Execute(context.Background(), event.Event{}))
+	assert.Equal(t, 0, clientCertificates)
+
+	trusted := validated(t, server.URL, func(http *Http) {
+		http.TLS = TLS{CA: certPath, Cert: certPath, Key: keyPath, MinVersion: "1.2"}
+	})
+	assert.NoError(t, trusted.Execute(context.Background(), event.Event{}))
+	assert.Equal(t, 1, clientCertificates)
+}
+
+func TestHttpTLSRetried(t *testing.T) {
+	server := httptest.NewTLSServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {}))
+	defer server.Close()
+
+	certPath, _ := writePEM(t, server)
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+
+	http := newTestHttp(logrus.New(), server.URL)
+	http.TLS = TLS{CA: caPath}
+	assert.Error(t, http.Execute(context.Background(), event.Event{}))
+
+	//The CA is read again once it exists
+	ca, err := os.ReadFile(certPath)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(caPath, ca, 0600))
+	assert.NoError(t, http.Execute(context.Background(), event.Event{}))
+}
+
+func TestHttpCloseIdleConnections(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	server := httptest.NewUnstartedServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {}))
+	server.Config.ConnState = func(conn net.Conn, state nethttp.ConnState) {
+		if state == nethttp.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	http := newTestHttp(logrus.New(), server.URL)
+	assert.NoError(t, http.Execute(context.Background(), event.Event{}))
+
+	//Connections are closed through the executors that decorate Http
+	pipeline := NewPipeline(logrus.New())
+	pipeline.Steps = []Step{{Name: "request", Executor: NewRetry(logrus.New(), http, config.Retry{})}}
+	CloseIdleConnections(pipeline)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("idle connection was not closed")
+	}
+}
+
+func TestHttpTLSValidate(t *testing.T) {
+	http := newTestHttp(logrus.New(), "https://example.com")
+	http.TLS = TLS{Cert: "cert.pem", MinVersion: "1.2"}
+	assert.Len(t, http.Validate(), 1)
+
+	http.TLS = TLS{CA: filepath.Join(t.TempDir(), "missing.pem")}
+	assert.Len(t, http.Validate(), 1)
+
+	http.TLS = TLS{MinVersion: "2.0"}
+	assert.Len(t, http.Validate(), 1)
+
+	//TLS errors are reported alongside the others
+	http.URL = ""
+	http.TLS = TLS{CA: filepath.Join(t.TempDir(), "missing.pem"), MinVersion: "2.0"}
+	assert.Len(t, http.Validate(), 3)
+	assert.Nil(t, http.client)
+}
+
+func TestHttpRedirects(t *testing.T) {
+	other := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.WriteHeader(nethttp.StatusTeapot)
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		switch r.URL.Path {
+		case "/other":
+			nethttp.Redirect(w, r, other.URL, nethttp.StatusFound)
+		case "/twice":
+			nethttp.Redirect(w, r, "/once", nethttp.StatusFound)
+		case "/once":
+			nethttp.Redirect(w, r, "/", nethttp.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	var statusErr *StatusError
+
+	follow := validated(t, server.URL+"/other", func(http *Http) {})
+	assert.ErrorAs(t, follow.Execute(context.Background(), event.Event{}), &statusErr)
+
+	sameHost := validated(t, server.URL+"/other", func(http *Http) { http.Redirects.Policy = SameHostRedirects })
+	assert.NoError(t, sameHost.Execute(context.Background(), event.Event{}))
+
+	limited := validated(t, server.URL+"/twice", func(http *Http) { http.Redirects.Max = 1 })
+	assert.Error(t, limited.Execute(context.Background(), event.Event{}))
+
+	none := validated(t, server.URL+"/twice", func(http *Http) {
+		http.Redirects.Policy = NoRedirects
+		http.Expect.Status = []config.StatusRange{"302"}
+	})
+	assert.NoError(t, none.Execute(context.Background(), event.Event{}))
+}
+
+func TestHttpProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	http := validated(t, "http://saucisson.invalid/path", func(http *Http) { http.Proxy = proxy.URL })
+	assert.NoError(t, http.Execute(context.Background(), event.Event{}))
+	assert.Equal(t, "http://saucisson.invalid/path", proxied)
+
+	http.Proxy = "not a url"
+	assert.Len(t, http.Validate(), 1)
+}
@@ -0,0 +1,84 @@
+package executor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is the location of a value within a JSON document. It is parsed
+// from a subset of JSONPath: keys, e.g. $.data.status, indexes of arrays,
+// e.g. $.items[0].id, and quoted keys, e.g. $['content-type']. The leading
+// $ is optional. Each element is a key, a string, or an index, an int
+type jsonPath []any
+
+// parseJSONPath parses path, see jsonPath
+func parseJSONPath(path string) (jsonPath, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if s != "" && s[0] != '.' && s[0] != '[' {
+		s = "." + s
+	}
+
+	var parsed jsonPath
+	for s != "" {
+		switch s[0] {
+		case '.':
+			end := strings.IndexAny(s[1:], ".[") + 1
+			if end == 0 {
+				end = len(s)
+			}
+			if end == 1 {
+				return nil, fmt.Errorf("%q has an empty key", path)
+			}
+			parsed = append(parsed, s[1:end])
+			s = s[end:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%q has an unclosed [", path)
+			}
+
+			inner := s[1:end]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				parsed = append(parsed, inner[1:len(inner)-1])
+			} else if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+				parsed = append(parsed, index)
+			} else {
+				return nil, fmt.Errorf("%q has an index that is not a number or quoted key", path)
+			}
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("%q is not a JSONPath, e.g. $.data.status", path)
+		}
+	}
+
+	return parsed, nil
+}
+
+// lookup returns the value at the path within document, a value decoded
+// by encoding/json, and whether it exists
+func (path jsonPath) lookup(document any) (any, bool) {
+	value := document
+
+	for _, element := range path {
+		switch element := element.(type) {
+		case string:
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			value, ok = object[element]
+			if !ok {
+				return nil, false
+			}
+		case int:
+			array, ok := value.([]any)
+			if !ok || element >= len(array) {
+				return nil, false
+			}
+			value = array[element]
+		}
+	}
+
+	return value, true
+}
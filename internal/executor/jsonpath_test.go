@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	cases := map[string]jsonPath{
+		"$":                   nil,
+		"$.a.b":               {"a", "b"},
+		"a.b":                 {"a", "b"},
+		"$.items[2].id":       {"items", 2, "id"},
+		"$['content-type'].x": {"content-type", "x"},
+		`["a.b"]`:             {"a.b"},
+	}
+
+	for path, expected := range cases {
+		parsed, err := parseJSONPath(path)
+		assert.NoError(t, err, path)
+		assert.Equal(t, expected, parsed, path)
+	}
+
+	for _, invalid := range []string{"$.", "$.a[", "$.a[-1]", "$a b[x]"} {
+		_, err := parseJSONPath(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestJSONPathLookup(t *testing.T) {
+	var document any
+	assert.NoError(t, json.Unmarshal([]byte(`{"items": [{"id": 1}, {"id": 2}]}`), &document))
+
+	value, ok := jsonPath{"items", 1, "id"}.lookup(document)
+	assert.True(t, ok)
+	assert.Equal(t, float64(2), value)
+
+	_, ok = jsonPath{"items", 2, "id"}.lookup(document)
+	assert.False(t, ok)
+
+	_, ok = jsonPath{"items", "id"}.lookup(document)
+	assert.False(t, ok)
+}
@@ -0,0 +1,202 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/mickyco94/saucisson/internal/config"
+)
+
+// limitsEnv holds the limits of a command while saucisson applies them
+// before executing the command, see RunLimited
+const limitsEnv = "SAUCISSON_LIMITS"
+
+// geteuid is the user saucisson runs as, which decides if nice can be negative
+var geteuid = os.Geteuid
+
+// IO scheduling classes of Limits.IONice
+const (
+	IORealtime   = "realtime"
+	IOBestEffort = "best-effort"
+	IOIdle       = "idle"
+)
+
+// Limits restricts the resources of a command and its children, zero values
+// are unlimited. AddressSpace, CPU, OpenFiles and Processes are applied with
+// setrlimit, CPU in seconds. Nice and IONice set the CPU and IO scheduling
+// priority. Limits are only supported on Linux
+type Limits struct {
+	AddressSpace config.ByteSize `yaml:"address_space" json:"address_space,omitempty"`
+	CPU          int             `yaml:"cpu" json:"cpu,omitempty"`
+	OpenFiles    int             `yaml:"open_files" json:"open_files,omitempty"`
+	Processes    int             `yaml:"processes" json:"processes,omitempty"`
+	Nice         int             `yaml:"nice" json:"nice,omitempty"`
+	IONice       string          `yaml:"ionice" json:"ionice,omitempty"`
+}
+
+// Validate checks that the limits are supported and in range
+func (limits *Limits) Validate() []error {
+	var errs []error
+
+	if runtime.GOOS != "linux" {
+		return append(errs, fmt.Errorf("are only supported on Linux"))
+	}
+
+	if limits.AddressSpace < 0 {
+		errs = append(errs, fmt.Errorf("address_space must not be negative"))
+	}
+	if limits.CPU < 0 {
+		errs = append(errs, fmt.Errorf("cpu must not be negative"))
+	}
+	if limits.OpenFiles < 0 {
+		errs = append(errs, fmt.Errorf("open_files must not be negative"))
+	}
+	if limits.Processes < 0 {
+		errs = append(errs, fmt.Errorf("processes must not be negative"))
+	}
+	if limits.Nice < -20 || limits.Nice > 19 {
+		errs = append(errs, fmt.Errorf("nice must be between -20 and 19"))
+	} else if limits.Nice < 0 && geteuid() != 0 {
+		errs = append(errs, fmt.Errorf("nice must not be negative unless saucisson runs as root"))
+	}
+
+	switch limits.IONice {
+	case "", IORealtime, IOBestEffort, IOIdle:
+	default:
+		errs = append(errs, fmt.Errorf("ionice %q is not one of realtime, best-effort or idle", limits.IONice))
+	}
+
+	return errs
+}
+
+// validateLimits adds the problems of limits, if any, to errs
+func validateLimits(errs []error, limits *Limits) []error {
+	if limits == nil {
+		return errs
+	}
+
+	for _, err := range limits.Validate() {
+		errs = append(errs, config.Invalid("limits", "%s", err.Error()))
+	}
+	return errs
+}
+
+// wrap makes cmd run saucisson first, which applies the limits and then
+// executes the command in its place, see RunLimited. A nil Limits noops
+func (limits *Limits) wrap(cmd *exec.Cmd) error {
+	if limits == nil {
+		return nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(limits)
+	if err != nil {
+		return err
+	}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+
+	cmd.Env = append(env, limitsEnv+"="+string(encoded))
+	cmd.Args = append([]string{self, cmd.Path}, cmd.Args...)
+	cmd.Path = self
+
+	return nil
+}
+
+// RunLimited applies the limits of a command and executes it, if saucisson
+// was started to do so by an executor with limits, otherwise it returns
+// immediately. It must be called at the very start of main
+func RunLimited() {
+	encoded, ok := os.LookupEnv(limitsEnv)
+	if !ok || len(os.Args) < 3 {
+		return
+	}
+
+	var limits Limits
+	err := json.Unmarshal([]byte(encoded), &limits)
+	if err == nil {
+		os.Unsetenv(limitsEnv)
+		//The arguments are the path, then the arguments of the command
+		err = execLimited(&limits, os.Args[1], os.Args[2:])
+	}
+
+	fmt.Fprintf(os.Stderr, "saucisson: limits: %v\n", err)
+	os.Exit(126)
+}
+
+// LimitError is returned when a command was stopped by one of its limits
+type LimitError struct {
+	// Limit is the yaml key of the limit, e.g. cpu
+	Limit string
+	// Possible is set when the limit is only the likely cause of the failure,
+	// from what the command printed, rather than how it exited
+	Possible bool
+	Err      error
+}
+
+func (err *LimitError) Error() string {
+	if err.Possible {
+		return fmt.Sprintf("%v, possibly because the %s limit was exceeded", err.Err, err.Limit)
+	}
+	return fmt.Sprintf("%s limit exceeded: %v", err.Limit, err.Err)
+}
+
+func (err *LimitError) Unwrap() error {
+	return err.Err
+}
+
+// limitMessages are the errors that commands commonly print when a limit
+// prevents them from continuing, by limit. Matched regardless of case. They
+// are printed for other reasons too, so only suggest a possible cause
+var limitMessages = []struct {
+	limit    string
+	messages []string
+}{
+	{"address_space", []string{"cannot allocate", "out of memory", "memory exhausted"}},
+	{"open_files", []string{"too many open files"}},
+	{"processes", []string{"resource temporarily unavailable", "fork: retry"}},
+}
+
+// exceeded returns the yaml key of the limit that caused the command to
+// fail with err, if any. The limit is known from the signal that killed the
+// command, otherwise it is possible from the end of its stderr
+func (limits *Limits) exceeded(err error, stderr string) (limit string, possible bool) {
+	if limits == nil || err == nil {
+		return "", false
+	}
+
+	if limit := limits.signalled(err); limit != "" {
+		return limit, false
+	}
+
+	configured := map[string]bool{
+		"address_space": limits.AddressSpace > 0,
+		"open_files":    limits.OpenFiles > 0,
+		"processes":     limits.Processes > 0,
+	}
+
+	stderr = strings.ToLower(stderr)
+	for _, candidate := range limitMessages {
+		if !configured[candidate.limit] {
+			continue
+		}
+		for _, message := range candidate.messages {
+			if strings.Contains(stderr, message) {
+				return candidate.limit, true
+			}
+		}
+	}
+
+	return "", false
+}
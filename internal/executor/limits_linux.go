@@ -0,0 +1,108 @@
+package executor
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+const (
+	// rlimitNproc is missing from syscall
+	rlimitNproc = 6
+
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+// ioClasses are the IO scheduling classes of ioprio_set
+var ioClasses = map[string]uintptr{
+	IORealtime:   1,
+	IOBestEffort: 2,
+	IOIdle:       3,
+}
+
+// execLimited applies limits to saucisson and replaces it with the command
+func execLimited(limits *Limits, path string, args []string) error {
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return err
+	}
+
+	if limits.Nice != 0 {
+		err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, limits.Nice)
+		if err != nil {
+			return err
+		}
+	}
+
+	if class, ok := ioClasses[limits.IONice]; ok {
+		//The default level of the class
+		level := uintptr(4)
+		if limits.IONice == IOIdle {
+			level = 0
+		}
+
+		_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, class<<ioprioClassShift|level)
+		if errno != 0 {
+			return errno
+		}
+	}
+
+	rlimits := []struct {
+		resource int
+		value    uint64
+	}{
+		{syscall.RLIMIT_CPU, uint64(limits.CPU)},
+		{syscall.RLIMIT_NOFILE, uint64(limits.OpenFiles)},
+		{rlimitNproc, uint64(limits.Processes)},
+		//Last, as saucisson itself may need more
+		{syscall.RLIMIT_AS, uint64(limits.AddressSpace)},
+	}
+
+	for _, rlimit := range rlimits {
+		if rlimit.value == 0 {
+			continue
+		}
+
+		max := rlimit.value
+		if rlimit.resource == syscall.RLIMIT_CPU {
+			//SIGXCPU at the soft limit identifies the limit, rather
+			//than SIGKILL at the hard limit
+			max++
+		}
+
+		err := syscall.Setrlimit(rlimit.resource, &syscall.Rlimit{Cur: rlimit.value, Max: max})
+		if err != nil {
+			return err
+		}
+	}
+
+	return syscall.Exec(path, args, syscall.Environ())
+}
+
+// signalled returns the limit that caused the command to be killed, if any
+func (limits *Limits) signalled(err error) string {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return ""
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+
+	switch status.Signal() {
+	case syscall.SIGXCPU:
+		if limits.CPU > 0 {
+			return "cpu"
+		}
+	case syscall.SIGSEGV, syscall.SIGBUS, syscall.SIGABRT:
+		//Failing to allocate the stack, or aborting when malloc fails
+		if limits.AddressSpace > 0 {
+			return "address_space"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package executor
+
+import "errors"
+
+// execLimited is unsupported where limits cannot be applied
+func execLimited(limits *Limits, path string, args []string) error {
+	return errors.New("limits are only supported on Linux")
+}
+
+// signalled never finds a limit where limits are unsupported
+func (limits *Limits) signalled(err error) string {
+	return ""
+}
@@ -0,0 +1,98 @@
+//go:build linux
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	//Executors with limits run the test binary to apply them
+	RunLimited()
+	os.Exit(m.Run())
+}
+
+// runLimited runs command in sh with limits, returning its stdout
+func runLimited(t *testing.T, limits *Limits, command string) (string, error) {
+	t.Helper()
+
+	shell := NewShell(logrus.New(), nil)
+	shell.Shell = "sh"
+	shell.Command = command
+	shell.Limits = limits
+
+	report := NewReport(1024)
+	err := shell.Execute(WithReport(context.Background(), report), event.Event{})
+	stdout, _, _ := report.Output()
+	return stdout, err
+}
+
+func TestLimitsApplied(t *testing.T) {
+	stdout, err := runLimited(t, &Limits{OpenFiles: 20, CPU: 30}, "ulimit -n; ulimit -t; echo $SAUCISSON_LIMITS")
+	assert.NoError(t, err)
+	assert.Equal(t, "20\n30\n\n", stdout)
+}
+
+func TestLimitsNice(t *testing.T) {
+	//The 19th field of stat is the nice value
+	stdout, err := runLimited(t, &Limits{Nice: 5}, "cut -d' ' -f19 /proc/self/stat")
+	assert.NoError(t, err)
+	assert.Equal(t, "5\n", stdout)
+}
+
+func TestLimitsCPUExceeded(t *testing.T) {
+	_, err := runLimited(t, &Limits{CPU: 1}, "while :; do :; done")
+
+	var limitErr *LimitError
+	if assert.ErrorAs(t, err, &limitErr) {
+		assert.Equal(t, "cpu", limitErr.Limit)
+		assert.False(t, limitErr.Possible)
+	}
+}
+
+func TestLimitsExceededMessage(t *testing.T) {
+	err := errors.New("exit status 1")
+
+	limit, possible := (&Limits{OpenFiles: 1}).exceeded(err, "open: Too many open files")
+	assert.Equal(t, "open_files", limit)
+	assert.True(t, possible)
+
+	limit, _ = (&Limits{CPU: 1}).exceeded(err, "open: Too many open files")
+	assert.Equal(t, "", limit)
+
+	limit, _ = (*Limits)(nil).exceeded(err, "open: Too many open files")
+	assert.Equal(t, "", limit)
+}
+
+func TestLimitsPossibleError(t *testing.T) {
+	_, err := runLimited(t, &Limits{OpenFiles: 64}, "echo 'open: Too many open files' >&2; exit 1")
+
+	var limitErr *LimitError
+	if assert.ErrorAs(t, err, &limitErr) {
+		assert.Equal(t, "open_files", limitErr.Limit)
+		assert.True(t, limitErr.Possible)
+		assert.Equal(t, "exit status 1, possibly because the open_files limit was exceeded", err.Error())
+	}
+}
+
+func TestLimitsValidate(t *testing.T) {
+	assert.Empty(t, (&Limits{AddressSpace: 1 << 30, Nice: 19, IONice: IOIdle}).Validate())
+	assert.Len(t, (&Limits{CPU: -1, Nice: 20, IONice: "fast"}).Validate(), 3)
+}
+
+func TestLimitsValidateNegativeNice(t *testing.T) {
+	defer func(euid func() int) { geteuid = euid }(geteuid)
+
+	geteuid = func() int { return 0 }
+	assert.Empty(t, (&Limits{Nice: -5}).Validate())
+
+	geteuid = func() int { return 1000 }
+	assert.Len(t, (&Limits{Nice: -5}).Validate(), 1)
+}
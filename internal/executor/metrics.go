@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"github.com/mickyco94/saucisson/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolWorkers = metrics.Factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "saucisson_pool_workers",
+		Help: "Number of workers of an executor pool",
+	}, []string{"pool"})
+	poolBusy = metrics.Factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "saucisson_pool_busy_workers",
+		Help: "Number of workers of an executor pool running a job",
+	}, []string{"pool"})
+	poolQueued = metrics.Factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "saucisson_pool_queued_jobs",
+		Help: "Number of jobs waiting for a worker of an executor pool",
+	}, []string{"pool"})
+	poolDropped = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "saucisson_pool_dropped_jobs_total",
+		Help: "Number of jobs dropped because the queue of an executor pool was full",
+	}, []string{"pool"})
+)
+
+// poolMetrics are the metrics of a single pool. They are kept by the pool,
+// rather than looked up by name, so that a pool that has been removed, see
+// Pool.RemoveMetrics, cannot affect a new pool of the same name
+type poolMetrics struct {
+	workers prometheus.Gauge
+	busy    prometheus.Gauge
+	queued  prometheus.Gauge
+	dropped prometheus.Counter
+}
+
+// newPoolMetrics returns the metrics of the pool with the name
+func newPoolMetrics(name string) poolMetrics {
+	return poolMetrics{
+		workers: poolWorkers.WithLabelValues(name),
+		busy:    poolBusy.WithLabelValues(name),
+		queued:  poolQueued.WithLabelValues(name),
+		dropped: poolDropped.WithLabelValues(name),
+	}
+}
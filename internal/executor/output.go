@@ -0,0 +1,141 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultMaxOutput is the output of a command that is captured, unless configured otherwise
+var DefaultMaxOutput = config.ByteSize(1 << 20)
+
+// outputLimit is the number of bytes of output that can still be captured
+// from a command, shared by its stdout and stderr
+type outputLimit struct {
+	mu        sync.Mutex
+	remaining int
+	exceeded  bool
+}
+
+func newOutputLimit(max int) *outputLimit {
+	return &outputLimit{remaining: max}
+}
+
+// take returns how many of n bytes can be captured
+func (limit *outputLimit) take(n int) int {
+	limit.mu.Lock()
+	defer limit.mu.Unlock()
+
+	if n > limit.remaining {
+		n = limit.remaining
+		limit.exceeded = true
+	}
+	limit.remaining -= n
+	return n
+}
+
+// Exceeded reports whether output was discarded
+func (limit *outputLimit) Exceeded() bool {
+	limit.mu.Lock()
+	defer limit.mu.Unlock()
+
+	return limit.exceeded
+}
+
+// limitedBuffer captures output up to its limit, the rest is discarded
+type limitedBuffer struct {
+	bytes.Buffer
+	limit *outputLimit
+}
+
+// Write never fails, so that the command is not interrupted by the limit
+func (buffer *limitedBuffer) Write(p []byte) (int, error) {
+	buffer.Buffer.Write(p[:buffer.limit.take(len(p))])
+	return len(p), nil
+}
+
+// limitedWriter writes output to Writer up to its limit, the rest is discarded
+type limitedWriter struct {
+	io.Writer
+	limit *outputLimit
+}
+
+// Write never fails, so that the command is not interrupted by the limit
+func (writer *limitedWriter) Write(p []byte) (int, error) {
+	if n := writer.limit.take(len(p)); n > 0 {
+		writer.Writer.Write(p[:n])
+	}
+	return len(p), nil
+}
+
+// lineLogger logs each line of a stream of output as it is written
+type lineLogger struct {
+	logger  logrus.FieldLogger
+	partial []byte
+}
+
+func newLineLogger(logger logrus.FieldLogger, stream string) *lineLogger {
+	return &lineLogger{
+		logger: logger.WithField("stream", stream),
+	}
+}
+
+// Write logs the complete lines of p, the remainder is logged once the
+// line is complete or Flush is called. Write never fails
+func (lines *lineLogger) Write(p []byte) (int, error) {
+	data := append(lines.partial, p...)
+
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		lines.logger.Info(string(bytes.TrimSuffix(data[:i], []byte("\r"))))
+		data = data[i+1:]
+	}
+
+	lines.partial = append(lines.partial[:0], data...)
+	return len(p), nil
+}
+
+// Flush logs the last line, if it was incomplete
+func (lines *lineLogger) Flush() {
+	if len(lines.partial) > 0 {
+		lines.logger.Info(string(lines.partial))
+		lines.partial = lines.partial[:0]
+	}
+}
+
+// tailSize is the amount of output kept by tailBuffer
+const tailSize = 512
+
+// tailBuffer keeps the end of the output written to it
+type tailBuffer struct {
+	buf []byte
+}
+
+// Write never fails
+func (tail *tailBuffer) Write(p []byte) (int, error) {
+	tail.buf = append(tail.buf, p...)
+	if len(tail.buf) > tailSize {
+		tail.buf = append(tail.buf[:0], tail.buf[len(tail.buf)-tailSize:]...)
+	}
+	return len(p), nil
+}
+
+// String returns the last lines of output, up to tailSize bytes
+func (tail *tailBuffer) String() string {
+	buf := tail.buf
+	if len(buf) == tailSize {
+		//The first line is likely to be partial
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 && i < len(buf)-1 {
+			buf = buf[i+1:]
+		}
+	}
+
+	return strings.TrimSpace(string(buf))
+}
@@ -0,0 +1,179 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+)
+
+// When makes a step conditional on the outcome of an earlier step
+// in the same pipeline. ExitCode is ignored if nil.
+type When struct {
+	Step     string
+	Status   config.StepStatus
+	ExitCode *int
+}
+
+// Step is a single executor that is run as part of a Pipeline
+type Step struct {
+	Name     string
+	Executor Executor
+
+	// ContinueOnError allows the pipeline to continue, and succeed,
+	// if this step fails
+	ContinueOnError bool
+
+	// When, if set, skips the step unless the condition is met
+	When *When
+}
+
+// stepResult records the outcome of a step for use by later steps
+type stepResult struct {
+	status   config.StepStatus
+	exitCode int
+}
+
+// Pipeline is an implementation of Executor that runs a number of
+// Steps sequentially.
+//
+// If a step fails, and does not continue on error, the remaining Steps
+// are skipped and the OnFailure steps are run. Finally steps are
+// always run last.
+type Pipeline struct {
+	logger logrus.FieldLogger
+
+	Steps     []Step
+	OnFailure []Step
+	Finally   []Step
+}
+
+// NewPipeline constructs an empty pipeline
+func NewPipeline(logger logrus.FieldLogger) *Pipeline {
+	return &Pipeline{
+		logger: logger,
+	}
+}
+
+// Execute runs each step of the pipeline in turn, logging the outcome of
+// every step. Every step receives the same event.
+// The returned error is that of the step that failed the pipeline
+func (pipeline *Pipeline) Execute(ctx context.Context, ev event.Event) error {
+	results := make(map[string]stepResult)
+
+	err := pipeline.run(ctx, ev, pipeline.Steps, results)
+
+	if err != nil {
+		// The pipeline has already failed, failures here only need logging
+		pipeline.run(ctx, ev, pipeline.OnFailure, results)
+	}
+
+	finallyErr := pipeline.run(ctx, ev, pipeline.Finally, results)
+	if err == nil {
+		err = finallyErr
+	}
+
+	return err
+}
+
+// CloseIdleConnections closes the idle connections of the executor of every step
+func (pipeline *Pipeline) CloseIdleConnections() {
+	for _, steps := range [][]Step{pipeline.Steps, pipeline.OnFailure, pipeline.Finally} {
+		for _, step := range steps {
+			CloseIdleConnections(step.Executor)
+		}
+	}
+}
+
+// run runs the steps in order, stopping at the first failing step that does
+// not continue on error. The outcome of each step is added to results
+func (pipeline *Pipeline) run(ctx context.Context, ev event.Event, steps []Step, results map[string]stepResult) error {
+	for _, step := range steps {
+		logger := pipeline.logger.WithField("step", step.Name)
+
+		if !step.When.matches(results) {
+			results[step.Name] = stepResult{status: config.Skipped}
+			logger.
+				WithField("status", config.Skipped).
+				Info("Pipeline step skipped")
+			continue
+		}
+
+		start := time.Now()
+		err := step.Executor.Execute(ctx, ev)
+
+		result := stepResult{status: config.Success, exitCode: ExitCode(err)}
+		if err != nil {
+			result.status = config.Failure
+		}
+		results[step.Name] = result
+
+		logger = logger.
+			WithField("status", result.status).
+			WithField("exit_code", result.exitCode).
+			WithField("duration", time.Since(start).String())
+
+		if err == nil {
+			logger.Info("Pipeline step completed")
+			continue
+		}
+
+		logger.WithError(err).Warn("Pipeline step failed")
+
+		if !step.ContinueOnError {
+			return fmt.Errorf("step %q: %w", step.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// matches reports whether the step should be run given the results of the
+// steps so far. A nil When always matches
+func (when *When) matches(results map[string]stepResult) bool {
+	if when == nil {
+		return true
+	}
+
+	result, exists := results[when.Step]
+	if !exists {
+		// The step was never reached, so it cannot have the expected outcome
+		return false
+	}
+
+	if when.Status != "" && result.status != when.Status {
+		return false
+	}
+
+	if when.ExitCode != nil && (result.status == config.Skipped || result.exitCode != *when.ExitCode) {
+		return false
+	}
+
+	return true
+}
+
+// ExitCode is the exit status of an executor that returned err.
+// A nil error is an exit code of 0, the exit code of a command is
+// used if available, otherwise any other error is an exit code of 1
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var execErr *ExitError
+	if errors.As(err, &execErr) {
+		return execErr.Code
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+
+	return 1
+}
@@ -0,0 +1,87 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder returns an executor that appends name to ran and returns err
+func recorder(ran *[]string, name string, err error) Executor {
+	return ExecutorFunc(func(ctx context.Context, ev event.Event) error {
+		*ran = append(*ran, name)
+		return err
+	})
+}
+
+func TestPipelineRunsStepsInOrder(t *testing.T) {
+	ran := []string{}
+
+	pipeline := NewPipeline(logrus.New())
+	pipeline.Steps = []Step{
+		{Name: "one", Executor: recorder(&ran, "one", nil)},
+		{Name: "two", Executor: recorder(&ran, "two", nil)},
+	}
+	pipeline.OnFailure = []Step{{Name: "failed", Executor: recorder(&ran, "failed", nil)}}
+	pipeline.Finally = []Step{{Name: "finally", Executor: recorder(&ran, "finally", nil)}}
+
+	err := pipeline.Execute(context.Background(), event.Event{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one", "two", "finally"}, ran)
+}
+
+func TestPipelineStopsAtFailure(t *testing.T) {
+	ran := []string{}
+	woopsie := errors.New("Woopsie")
+
+	pipeline := NewPipeline(logrus.New())
+	pipeline.Steps = []Step{
+		{Name: "one", Executor: recorder(&ran, "one", woopsie)},
+		{Name: "two", Executor: recorder(&ran, "two", nil)},
+	}
+	pipeline.OnFailure = []Step{{Name: "failed", Executor: recorder(&ran, "failed", nil)}}
+	pipeline.Finally = []Step{{Name: "finally", Executor: recorder(&ran, "finally", nil)}}
+
+	err := pipeline.Execute(context.Background(), event.Event{})
+
+	assert.ErrorIs(t, err, woopsie)
+	assert.Equal(t, []string{"one", "failed", "finally"}, ran)
+}
+
+func TestPipelineContinueOnError(t *testing.T) {
+	ran := []string{}
+
+	exitCode := 1
+
+	pipeline := NewPipeline(logrus.New())
+	pipeline.Steps = []Step{
+		{Name: "one", Executor: recorder(&ran, "one", errors.New("Woopsie")), ContinueOnError: true},
+		{Name: "success", Executor: recorder(&ran, "success", nil), When: &When{Step: "one", Status: config.Success}},
+		{Name: "exit", Executor: recorder(&ran, "exit", nil), When: &When{Step: "one", ExitCode: &exitCode}},
+		{Name: "skipped", Executor: recorder(&ran, "skipped", nil), When: &When{Step: "success", Status: config.Skipped}},
+	}
+
+	err := pipeline.Execute(context.Background(), event.Event{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one", "exit", "skipped"}, ran)
+}
+
+func TestPipelineFinallyFailure(t *testing.T) {
+	ran := []string{}
+	woopsie := errors.New("Woopsie")
+
+	pipeline := NewPipeline(logrus.New())
+	pipeline.Steps = []Step{{Name: "one", Executor: recorder(&ran, "one", nil)}}
+	pipeline.Finally = []Step{{Name: "finally", Executor: recorder(&ran, "finally", woopsie)}}
+
+	err := pipeline.Execute(context.Background(), event.Event{})
+
+	assert.ErrorIs(t, err, woopsie)
+}
@@ -2,8 +2,12 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
 
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
 	"github.com/sirupsen/logrus"
 )
 
@@ -13,49 +17,132 @@ import (
 //
 //	pool.Enqueue(job)
 type Job struct {
+	// ID identifies the job in logs and the history, see NewJobID
+	ID       string
 	Service  string
+	Event    event.Event
 	Executor ExecutorFunc
+	// Dropped, if set, is called if the job is removed from the queue
+	// to make room for a newer job, see config.DropOldest
+	Dropped func()
 }
 
 // Pool represents a collection of workers that can be used
-// by `executor.Execute` to dispatch work
+// by `executor.Execute` to dispatch work.
+//
+// Jobs wait in a bounded queue until a worker is idle. What happens to a job
+// that is enqueued while the queue is full is determined by the overflow policy
 type Pool struct {
 	logger logrus.FieldLogger
 	ctx    context.Context
 	cancel context.CancelFunc
 
+	//name labels the metrics of the pool
+	name    string
+	metrics poolMetrics
+
+	//runningMu guards the queue and the state of the pool,
+	//changed is broadcast whenever either changes
 	runningMu sync.Mutex
+	changed   *sync.Cond
 	running   bool
+	stopped   bool
+
+	queue      []Job
+	queueDepth int
+	overflow   config.Overflow
+	dropped    uint64
 
-	size int
-	wg   sync.WaitGroup
-	jobs chan Job
+	//size is the number of workers the pool should have, workers is the
+	//number it has, these differ briefly when the pool is resized
+	size    int
+	workers int
+	wg      sync.WaitGroup
+
+	//busy is the number of workers running a job, accessed atomically
+	busy int64
 }
 
 // DefaultPoolSize represents the total number of goroutines
-// that this executor pool shares. In the future this may be configurable
-// and/or dependent on the number of services specified in configuration
+// that this executor pool shares, unless configured otherwise
 var DefaultPoolSize = 15
 
-// NewPool constructs a new executor pool
-func NewPool(logger logrus.FieldLogger, size int) *Pool {
+// DefaultPoolQueueDepth is the number of jobs that can wait for a worker
+var DefaultPoolQueueDepth = 100
+
+// ErrQueueFull is returned when a job is dropped because the queue is full
+var ErrQueueFull = errors.New("Executor queue is full")
+
+// ErrPoolStopped is returned when a job is enqueued after the pool is stopped
+var ErrPoolStopped = errors.New("Executor pool is stopped")
+
+// NewPool constructs a new executor pool, with the default queue depth
+// and the block overflow policy. name labels the metrics of the pool
+func NewPool(logger logrus.FieldLogger, name string, size int) *Pool {
 	localCtx, cancel := context.WithCancel(context.Background())
 
-	return &Pool{
-		ctx:       localCtx,
-		cancel:    cancel,
-		size:      size,
-		wg:        sync.WaitGroup{},
-		running:   false,
-		runningMu: sync.Mutex{},
-		logger:    logger,
-		jobs:      make(chan Job),
+	pool := &Pool{
+		ctx:        localCtx,
+		cancel:     cancel,
+		name:       name,
+		metrics:    newPoolMetrics(name),
+		size:       size,
+		wg:         sync.WaitGroup{},
+		running:    false,
+		runningMu:  sync.Mutex{},
+		logger:     logger,
+		queueDepth: DefaultPoolQueueDepth,
+		overflow:   config.Block,
+	}
+	pool.changed = sync.NewCond(&pool.runningMu)
+	pool.metrics.workers.Set(float64(size))
+
+	return pool
+}
+
+// RemoveMetrics stops reporting the metrics of the pool, once it has been
+// removed. The metrics of a new pool with the same name are unaffected
+func (pool *Pool) RemoveMetrics() {
+	poolWorkers.DeleteLabelValues(pool.name)
+	poolBusy.DeleteLabelValues(pool.name)
+	poolQueued.DeleteLabelValues(pool.name)
+	poolDropped.DeleteLabelValues(pool.name)
+}
+
+// Configure sets the size, queue depth and overflow policy of the pool,
+// zero values restore the defaults. It can be called while the pool is
+// running: workers are started or stopped, once idle, to match the size.
+// Jobs already queued beyond a reduced depth are not dropped
+func (pool *Pool) Configure(cfg config.Pool) {
+	pool.runningMu.Lock()
+	defer pool.runningMu.Unlock()
+
+	if cfg.Size <= 0 {
+		cfg.Size = DefaultPoolSize
+	}
+	if cfg.QueueDepth <= 0 {
+		cfg.QueueDepth = DefaultPoolQueueDepth
+	}
+	if cfg.Overflow == "" {
+		cfg.Overflow = config.Block
+	}
+
+	pool.size = cfg.Size
+	pool.queueDepth = cfg.QueueDepth
+	pool.overflow = cfg.Overflow
+	pool.metrics.workers.Set(float64(cfg.Size))
+
+	if pool.running {
+		pool.startWorkers()
 	}
+
+	pool.changed.Broadcast()
 }
 
 // Stop closes all running goroutines that are members of the
 // execution pool, each executor is also instructured to cancel
-// execution by an internal context.
+// execution by an internal context. Jobs that are still queued
+// are run with the cancelled context.
 //
 // The context passed to the Stop method can be used to abort the shutdown of
 // the executor pool
@@ -66,6 +153,11 @@ func NewPool(logger logrus.FieldLogger, size int) *Pool {
 func (pool *Pool) Stop(ctx context.Context) error {
 	pool.runningMu.Lock()
 
+	//Stop accepting new jobs, even if the pool was never started, so that
+	//blocked enqueuers are woken
+	pool.stopped = true
+	pool.changed.Broadcast()
+
 	if !pool.running {
 		pool.runningMu.Unlock()
 		return nil
@@ -81,8 +173,6 @@ func (pool *Pool) Stop(ctx context.Context) error {
 		cancel()
 	}()
 
-	//Stop sending new jobs
-	close(pool.jobs)
 	//Cancel all running jobs
 	pool.cancel()
 
@@ -102,54 +192,199 @@ func (pool *Pool) Stop(ctx context.Context) error {
 //	pool.Stop(context.Context)
 func (pool *Pool) Start() {
 	pool.runningMu.Lock()
-	if pool.running {
+	if pool.running || pool.stopped {
 		pool.runningMu.Unlock()
 		return
 	}
 
 	pool.running = true
+	pool.startWorkers()
 	pool.runningMu.Unlock()
+}
 
-	pool.wg.Add(pool.size)
+// startWorkers starts workers until there are as many as the size of the pool.
+// Must be called with runningMu held
+func (pool *Pool) startWorkers() {
+	for ; pool.workers < pool.size; pool.workers++ {
+		pool.wg.Add(1)
 
-	for i := 0; i < pool.size; i++ {
 		go func() {
 			defer func() {
 				pool.wg.Done()
 			}()
 
-			for job := range pool.jobs {
+			for {
+				job, ok := pool.next()
+				if !ok {
+					return
+				}
 				pool.run(&job)
 			}
 		}()
 	}
 }
 
-// Enqueue adds the execution to the queue
-func (pool *Pool) Enqueue(job Job) {
-	pool.jobs <- job
+// next waits for a job to be queued. false is returned, and the worker
+// should exit, once the pool is stopped and the queue is empty or if the
+// pool has more workers than its size
+func (pool *Pool) next() (Job, bool) {
+	pool.runningMu.Lock()
+	defer pool.runningMu.Unlock()
+
+	for len(pool.queue) == 0 || pool.workers > pool.size {
+		if pool.workers > pool.size || (pool.stopped && len(pool.queue) == 0) {
+			pool.workers--
+			return Job{}, false
+		}
+		pool.changed.Wait()
+	}
+
+	job := pool.queue[0]
+	pool.queue[0] = Job{}
+	pool.queue = pool.queue[1:]
+	pool.metrics.queued.Set(float64(len(pool.queue)))
+	pool.changed.Broadcast()
+
+	return job, true
+}
+
+// Enqueue adds the job to the queue. If the queue is full the job is handled
+// according to the overflow policy: block waits for space, drop-newest
+// returns ErrQueueFull and drop-oldest makes room by dropping the job that
+// has been waiting longest.
+// ErrPoolStopped is returned once the pool has been stopped
+func (pool *Pool) Enqueue(job Job) error {
+	return pool.enqueue(job, true)
+}
+
+// TryEnqueue adds the job to the queue without blocking. If the queue is full
+// ErrQueueFull is returned, unless the overflow policy is drop-oldest
+func (pool *Pool) TryEnqueue(job Job) error {
+	return pool.enqueue(job, false)
+}
+
+func (pool *Pool) enqueue(job Job, wait bool) error {
+	pool.runningMu.Lock()
+
+	blocked := false
+	for !pool.stopped && len(pool.queue) >= pool.queueDepth && wait && pool.overflow == config.Block {
+		if !blocked {
+			blocked = true
+			pool.logger.
+				WithField("svc", job.Service).
+				WithField("queued", len(pool.queue)).
+				Warn("Executor queue is full, waiting")
+		}
+		pool.changed.Wait()
+	}
+
+	if pool.stopped {
+		pool.runningMu.Unlock()
+		return ErrPoolStopped
+	}
+
+	var evicted *Job
+
+	if len(pool.queue) >= pool.queueDepth {
+		if pool.overflow != config.DropOldest {
+			pool.dropped++
+			pool.metrics.dropped.Inc()
+			dropped := pool.dropped
+			pool.runningMu.Unlock()
+
+			pool.logger.
+				WithField("svc", job.Service).
+				WithField("dropped", dropped).
+				Warn("Executor queue is full, dropped job")
+			return ErrQueueFull
+		}
+
+		oldest := pool.queue[0]
+		evicted = &oldest
+		pool.queue = pool.queue[1:]
+		pool.dropped++
+		pool.metrics.dropped.Inc()
+	}
+
+	pool.queue = append(pool.queue, job)
+	pool.metrics.queued.Set(float64(len(pool.queue)))
+	dropped := pool.dropped
+	pool.changed.Broadcast()
+	pool.runningMu.Unlock()
+
+	if evicted != nil {
+		pool.logger.
+			WithField("svc", evicted.Service).
+			WithField("dropped", dropped).
+			Warn("Executor queue is full, dropped oldest job")
+
+		if evicted.Dropped != nil {
+			evicted.Dropped()
+		}
+	}
+
+	return nil
+}
+
+// Stats is a snapshot of the utilisation of the pool
+type Stats struct {
+	Size int
+	Busy int
+	// Queued is the number of jobs waiting for a worker
+	Queued int
+	// Dropped is the number of jobs dropped because the queue was full
+	Dropped uint64
+}
+
+// Stats returns the current utilisation of the pool
+func (pool *Pool) Stats() Stats {
+	pool.runningMu.Lock()
+	defer pool.runningMu.Unlock()
+
+	return Stats{
+		Size:    pool.size,
+		Busy:    int(atomic.LoadInt64(&pool.busy)),
+		Queued:  len(pool.queue),
+		Dropped: pool.dropped,
+	}
 }
 
 // run executes a job on the pool and manages all error handling
 func (pool *Pool) run(job *Job) {
+	atomic.AddInt64(&pool.busy, 1)
+	pool.metrics.busy.Inc()
+	defer func() {
+		atomic.AddInt64(&pool.busy, -1)
+		pool.metrics.busy.Dec()
+	}()
+
 	defer func() {
 		if rec := recover(); rec != nil {
 			pool.logger.
 				WithField("svc", job.Service).
+				WithField("job", job.ID).
 				WithField("panic", rec).
 				Error("Executor panicked")
 		}
 	}()
 
-	err := job.Executor(pool.ctx)
+	err := job.Executor(pool.ctx, job.Event)
 	if err != nil {
-		pool.logger.
+		logger := pool.logger.
 			WithError(err).
 			WithField("svc", job.Service).
-			Error("Execution failed")
+			WithField("job", job.ID)
+
+		var stderrErr *StderrError
+		if errors.As(err, &stderrErr) {
+			logger = logger.WithField("stderr", stderrErr.Tail)
+		}
+
+		logger.Error("Execution failed")
 	} else {
 		pool.logger.
 			WithField("svc", job.Service).
+			WithField("job", job.ID).
 			Info("Execution completed")
 	}
 }
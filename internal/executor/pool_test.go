@@ -6,12 +6,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestStartStopMultiThread(t *testing.T) {
-	pool := NewPool(logrus.New(), DefaultPoolSize)
+	pool := NewPool(logrus.New(), t.Name(), DefaultPoolSize)
 
 	pool.Start()
 
@@ -21,7 +24,7 @@ func TestStartStopMultiThread(t *testing.T) {
 }
 
 func TestStopCancelledContext(t *testing.T) {
-	pool := NewPool(logrus.New(), DefaultPoolSize)
+	pool := NewPool(logrus.New(), t.Name(), DefaultPoolSize)
 
 	pool.Start()
 
@@ -35,7 +38,7 @@ func TestStopCancelledContext(t *testing.T) {
 }
 
 func TestStopRunningExecutors(t *testing.T) {
-	pool := NewPool(logrus.New(), 1)
+	pool := NewPool(logrus.New(), t.Name(), 1)
 
 	pool.Start()
 
@@ -43,7 +46,7 @@ func TestStopRunningExecutors(t *testing.T) {
 
 	pool.Enqueue(Job{
 		Service: "test",
-		Executor: func(ctx context.Context) error {
+		Executor: func(ctx context.Context, ev event.Event) error {
 			time.Sleep(500 * time.Millisecond)
 			done = true
 			return nil
@@ -59,13 +62,13 @@ func TestStopRunningExecutors(t *testing.T) {
 }
 
 func TestExecutorCanError(t *testing.T) {
-	pool := NewPool(logrus.New(), 1)
+	pool := NewPool(logrus.New(), t.Name(), 1)
 
 	pool.Start()
 
 	pool.Enqueue(Job{
 		Service: "test",
-		Executor: func(ctx context.Context) error {
+		Executor: func(ctx context.Context, ev event.Event) error {
 			return errors.New("Woopsie")
 		},
 	})
@@ -74,13 +77,13 @@ func TestExecutorCanError(t *testing.T) {
 }
 
 func TestExecutorRecover(t *testing.T) {
-	pool := NewPool(logrus.New(), 1)
+	pool := NewPool(logrus.New(), t.Name(), 1)
 
 	pool.Start()
 
 	pool.Enqueue(Job{
 		Service: "panic",
-		Executor: func(ctx context.Context) error {
+		Executor: func(ctx context.Context, ev event.Event) error {
 			panic("Panicking!")
 		},
 	})
@@ -89,3 +92,201 @@ func TestExecutorRecover(t *testing.T) {
 	//No way to assert that there are n go-routines still running
 	assert.True(t, pool.running)
 }
+
+func TestStatsBusy(t *testing.T) {
+	pool := NewPool(logrus.New(), t.Name(), 2)
+
+	pool.Start()
+	defer pool.Stop(context.Background())
+
+	release := make(chan struct{})
+
+	pool.Enqueue(Job{
+		Service: "test",
+		Executor: func(ctx context.Context, ev event.Event) error {
+			<-release
+			return nil
+		},
+	})
+
+	assert.Eventually(t, func() bool {
+		return pool.Stats() == Stats{Size: 2, Busy: 1}
+	}, time.Second, time.Millisecond)
+
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		return pool.Stats().Busy == 0
+	}, time.Second, time.Millisecond)
+}
+
+func noop(ctx context.Context, ev event.Event) error { return nil }
+
+func TestEnqueueAfterStop(t *testing.T) {
+	pool := NewPool(logrus.New(), t.Name(), 1)
+
+	pool.Start()
+	pool.Stop(context.Background())
+
+	err := pool.Enqueue(Job{Service: "test", Executor: noop})
+
+	assert.ErrorIs(t, err, ErrPoolStopped)
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	pool := NewPool(logrus.New(), t.Name(), 1)
+	pool.Configure(config.Pool{Size: 1, QueueDepth: 1, Overflow: config.Block})
+
+	assert.NoError(t, pool.Enqueue(Job{Service: "test", Executor: noop}))
+
+	enqueued := make(chan error)
+	go func() {
+		enqueued <- pool.Enqueue(Job{Service: "test", Executor: noop})
+	}()
+
+	assert.NoError(t, pool.Stop(context.Background()))
+
+	select {
+	case err := <-enqueued:
+		assert.ErrorIs(t, err, ErrPoolStopped)
+	case <-time.After(time.Second):
+		t.Fatal("enqueue was not unblocked")
+	}
+
+	assert.ErrorIs(t, pool.Enqueue(Job{Service: "test", Executor: noop}), ErrPoolStopped)
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	pool := NewPool(logrus.New(), t.Name(), 1)
+	pool.Configure(config.Pool{Size: 1, QueueDepth: 1, Overflow: config.DropNewest})
+
+	assert.NoError(t, pool.Enqueue(Job{Service: "test", Executor: noop}))
+	assert.ErrorIs(t, pool.Enqueue(Job{Service: "test", Executor: noop}), ErrQueueFull)
+
+	assert.Equal(t, Stats{Size: 1, Queued: 1, Dropped: 1}, pool.Stats())
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	pool := NewPool(logrus.New(), t.Name(), 1)
+	pool.Configure(config.Pool{Size: 1, QueueDepth: 1, Overflow: config.DropOldest})
+
+	dropped := false
+
+	assert.NoError(t, pool.Enqueue(Job{Service: "oldest", Executor: noop, Dropped: func() { dropped = true }}))
+	assert.NoError(t, pool.Enqueue(Job{Service: "newest", Executor: noop}))
+
+	assert.True(t, dropped)
+	assert.Equal(t, "newest", pool.queue[0].Service)
+	assert.Equal(t, Stats{Size: 1, Queued: 1, Dropped: 1}, pool.Stats())
+}
+
+func TestOverflowBlock(t *testing.T) {
+	pool := NewPool(logrus.New(), t.Name(), 1)
+	pool.Configure(config.Pool{Size: 1, QueueDepth: 1, Overflow: config.Block})
+
+	release := make(chan struct{})
+	pool.Start()
+
+	assert.NoError(t, pool.Enqueue(Job{
+		Service: "test",
+		Executor: func(ctx context.Context, ev event.Event) error {
+			<-release
+			return nil
+		},
+	}))
+
+	//Fill the queue once the worker is busy
+	assert.Eventually(t, func() bool { return pool.Stats().Busy == 1 }, time.Second, time.Millisecond)
+	assert.NoError(t, pool.Enqueue(Job{Service: "test", Executor: noop}))
+
+	//TryEnqueue never blocks
+	assert.ErrorIs(t, pool.TryEnqueue(Job{Service: "test", Executor: noop}), ErrQueueFull)
+
+	enqueued := make(chan error)
+	go func() {
+		enqueued <- pool.Enqueue(Job{Service: "test", Executor: noop})
+	}()
+
+	select {
+	case <-enqueued:
+		t.Fatal("enqueue did not block while the queue was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case err := <-enqueued:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("enqueue was not unblocked")
+	}
+
+	pool.Stop(context.Background())
+}
+
+func TestConfigureResize(t *testing.T) {
+	pool := NewPool(logrus.New(), t.Name(), 1)
+	pool.Start()
+	defer pool.Stop(context.Background())
+
+	pool.Configure(config.Pool{Size: 3})
+
+	release := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, pool.Enqueue(Job{
+			Service: "test",
+			Executor: func(ctx context.Context, ev event.Event) error {
+				<-release
+				return nil
+			},
+		}))
+	}
+
+	assert.Eventually(t, func() bool { return pool.Stats().Busy == 3 }, time.Second, time.Millisecond)
+
+	pool.Configure(config.Pool{Size: 1})
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		pool.runningMu.Lock()
+		defer pool.runningMu.Unlock()
+		return pool.workers == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestPoolMetrics(t *testing.T) {
+	pool := NewPool(logrus.New(), t.Name(), 1)
+	pool.Configure(config.Pool{Size: 1, QueueDepth: 1, Overflow: config.DropNewest})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(poolWorkers.WithLabelValues(t.Name())))
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	blocking := func(ctx context.Context, ev event.Event) error {
+		close(started)
+		<-release
+		return nil
+	}
+
+	pool.Start()
+	assert.NoError(t, pool.Enqueue(Job{Executor: blocking}))
+	<-started
+
+	assert.NoError(t, pool.Enqueue(Job{Executor: func(context.Context, event.Event) error { return nil }}))
+	assert.ErrorIs(t, pool.Enqueue(Job{}), ErrQueueFull)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(poolBusy.WithLabelValues(t.Name())))
+	assert.Equal(t, 1.0, testutil.ToFloat64(poolQueued.WithLabelValues(t.Name())))
+	assert.Equal(t, 1.0, testutil.ToFloat64(poolDropped.WithLabelValues(t.Name())))
+
+	close(release)
+	assert.NoError(t, pool.Stop(context.Background()))
+
+	assert.Equal(t, 0.0, testutil.ToFloat64(poolBusy.WithLabelValues(t.Name())))
+	assert.Equal(t, 0.0, testutil.ToFloat64(poolQueued.WithLabelValues(t.Name())))
+
+	pools := testutil.CollectAndCount(poolWorkers)
+	pool.RemoveMetrics()
+	assert.Equal(t, pools-1, testutil.CollectAndCount(poolWorkers))
+}
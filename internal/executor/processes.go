@@ -0,0 +1,130 @@
+package executor
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Processes tracks the processes started by Spawn executors. It is shared
+// by every Spawn so that processes are still tracked after the config is
+// reloaded, and can be stopped when saucisson exits.
+type Processes struct {
+	logger logrus.FieldLogger
+
+	mu    sync.Mutex
+	procs map[int]*spawned
+}
+
+// spawned is a process that has been started and not yet exited
+type spawned struct {
+	service        string
+	stopOnShutdown bool
+	//exited is closed once the process has been reaped
+	exited chan struct{}
+}
+
+// NewProcesses constructs an empty set of processes
+func NewProcesses(logger logrus.FieldLogger) *Processes {
+	return &Processes{
+		logger: logger,
+		procs:  make(map[int]*spawned),
+	}
+}
+
+// running returns the pid of a running process of the service, if any.
+// Must be called with mu held
+func (p *Processes) running(service string) (int, bool) {
+	for pid, proc := range p.procs {
+		if proc.service == service {
+			return pid, true
+		}
+	}
+	return 0, false
+}
+
+// start starts cmd on behalf of the service and reaps it once it exits.
+// If singleInstance is set and the service already has a running process,
+// cmd is not started and the pid of the running process is returned
+func (p *Processes) start(service string, cmd *exec.Cmd, singleInstance bool, stopOnShutdown bool) (pid int, started bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if singleInstance {
+		if pid, running := p.running(service); running {
+			return pid, false, nil
+		}
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return 0, false, err
+	}
+
+	pid = cmd.Process.Pid
+	proc := &spawned{
+		service:        service,
+		stopOnShutdown: stopOnShutdown,
+		exited:         make(chan struct{}),
+	}
+	p.procs[pid] = proc
+
+	go func() {
+		err := cmd.Wait()
+
+		p.mu.Lock()
+		delete(p.procs, pid)
+		p.mu.Unlock()
+		close(proc.exited)
+
+		logger := p.logger.
+			WithField("svc", service).
+			WithField("pid", pid).
+			WithField("exit_code", ExitCode(err))
+		if err != nil {
+			logger.WithError(err).Warn("Spawned process exited")
+		} else {
+			logger.Info("Spawned process exited")
+		}
+	}()
+
+	return pid, true, nil
+}
+
+// Stop sends SIGTERM to the process group of every process that was spawned
+// with stop_on_shutdown and waits for them to exit. Any that have not exited
+// when ctx is done are sent SIGKILL
+func (p *Processes) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	stopping := make(map[int]*spawned)
+	for pid, proc := range p.procs {
+		if proc.stopOnShutdown {
+			stopping[pid] = proc
+		}
+	}
+	p.mu.Unlock()
+
+	for pid, proc := range stopping {
+		err := signalGroup(pid, syscall.SIGTERM)
+		if err != nil {
+			p.logger.
+				WithError(err).
+				WithField("svc", proc.service).
+				WithField("pid", pid).
+				Error("Failed to stop spawned process")
+		}
+	}
+
+	for pid, proc := range stopping {
+		select {
+		case <-proc.exited:
+		case <-ctx.Done():
+			signalGroup(pid, syscall.SIGKILL)
+		}
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,232 @@
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// NewJobID generates a random identifier for a job
+func NewJobID() string {
+	id := make([]byte, 8)
+	_, err := rand.Read(id)
+	if err != nil {
+		// Unreachable on supported platforms, the id is only informational
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
+
+type jobKey struct{}
+
+// WithJobID returns a copy of ctx for the execution of the job id
+func WithJobID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, jobKey{}, id)
+}
+
+// jobID returns the id of the job of ctx, empty if there is none
+func jobID(ctx context.Context) string {
+	id, _ := ctx.Value(jobKey{}).(string)
+	return id
+}
+
+// Report collects what an execution produced beyond its error, so that it can
+// be recorded in the history. Executors add to the report of their context,
+// if any, see WithReport. Output beyond the limit is discarded from the report,
+// but is still written to the log of the report, see SetLog
+type Report struct {
+	mu          sync.Mutex
+	limit       int
+	stdout      []byte
+	stderr      []byte
+	tail        tailBuffer
+	truncated   bool
+	httpStatus  int
+	termination Termination
+	log         Log
+}
+
+// Log receives the output of an execution as it is written. stream is
+// Stdout or Stderr for the output of commands, or HttpStream for requests
+type Log interface {
+	WriteStream(stream string, p []byte)
+}
+
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+	// HttpStream is the stream of requests and their response status
+	HttpStream = "http"
+)
+
+// NewReport constructs a report that keeps up to limit bytes of each of stdout and stderr
+func NewReport(limit int) *Report {
+	return &Report{limit: limit}
+}
+
+// SetLog sets the log that output is written to, in full
+func (report *Report) SetLog(log Log) {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	report.log = log
+}
+
+type reportKey struct{}
+
+// WithReport returns a copy of ctx that executors add their output to
+func WithReport(ctx context.Context, report *Report) context.Context {
+	return context.WithValue(ctx, reportKey{}, report)
+}
+
+// reportFrom returns the report of ctx, nil if there is none
+func reportFrom(ctx context.Context) *Report {
+	report, _ := ctx.Value(reportKey{}).(*Report)
+	return report
+}
+
+// outputWriters returns writers that add the stdout and stderr of a command
+// to the report of ctx as it is written. The output of every step of a
+// pipeline is kept, in order. Output is discarded if ctx has no report
+func outputWriters(ctx context.Context) (stdout io.Writer, stderr io.Writer) {
+	report := reportFrom(ctx)
+	if report == nil {
+		return io.Discard, io.Discard
+	}
+
+	return &reportWriter{report: report, stream: Stdout}, &reportWriter{report: report, stream: Stderr}
+}
+
+// reportWriter writes a stream of output to a report
+type reportWriter struct {
+	report *Report
+	stream string
+}
+
+// Write adds p to the report, it never fails so that commands are not
+// interrupted by the report
+func (writer *reportWriter) Write(p []byte) (int, error) {
+	report := writer.report
+
+	report.mu.Lock()
+	if writer.stream == Stdout {
+		report.stdout = report.append(report.stdout, p)
+	} else {
+		report.stderr = report.append(report.stderr, p)
+		report.tail.Write(p)
+	}
+	log := report.log
+	report.mu.Unlock()
+
+	if log != nil {
+		log.WriteStream(writer.stream, p)
+	}
+
+	return len(p), nil
+}
+
+// reportRequest writes a line to the log of the report of ctx, describing a request
+func reportRequest(ctx context.Context, format string, args ...any) {
+	report := reportFrom(ctx)
+	if report == nil {
+		return
+	}
+
+	report.mu.Lock()
+	log := report.log
+	report.mu.Unlock()
+
+	if log != nil {
+		log.WriteStream(HttpStream, []byte(fmt.Sprintf(format, args...)+"\n"))
+	}
+}
+
+// reportStatus records the status of a HTTP response in the report of ctx
+func reportStatus(ctx context.Context, status int) {
+	report := reportFrom(ctx)
+	if report == nil {
+		return
+	}
+
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	report.httpStatus = status
+}
+
+// reportTermination records how a command exited in the report of ctx,
+// a pipeline keeps the most severe of its commands
+func reportTermination(ctx context.Context, termination Termination) {
+	report := reportFrom(ctx)
+	if report == nil {
+		return
+	}
+
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	if termination.severity() > report.termination.severity() {
+		report.termination = termination
+	}
+}
+
+// append adds out to buf up to the limit. Must be called with mu held
+func (report *Report) append(buf []byte, out []byte) []byte {
+	space := report.limit - len(buf)
+	if len(out) > space {
+		out = out[:space]
+		report.truncated = true
+	}
+	return append(buf, out...)
+}
+
+// Output returns the stdout and stderr collected, and whether either was truncated
+func (report *Report) Output() (stdout string, stderr string, truncated bool) {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	return string(report.stdout), string(report.stderr), report.truncated
+}
+
+// HttpStatus returns the status of the last HTTP response, 0 if there was none
+func (report *Report) HttpStatus() int {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	return report.httpStatus
+}
+
+// Termination returns how the commands of the execution exited, or
+// whether they were stopped. Empty if no command was run
+func (report *Report) Termination() Termination {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	return report.termination
+}
+
+// StderrTail returns the last lines of stderr, up to a few hundred bytes
+func (report *Report) StderrTail() string {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	return report.tail.String()
+}
+
+// StderrError is the error of an execution along with the end of its
+// stderr, so that the cause of a failure is logged alongside it
+type StderrError struct {
+	Err  error
+	Tail string
+}
+
+func (err *StderrError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *StderrError) Unwrap() error {
+	return err.Err
+}
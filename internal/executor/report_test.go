@@ -0,0 +1,88 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportShellOutput(t *testing.T) {
+	shell := NewShell(logrus.New(), nil)
+	shell.Shell = "sh"
+	shell.Command = "echo out; echo err >&2; exit 1"
+
+	report := NewReport(1024)
+	err := shell.Execute(WithReport(context.Background(), report), event.Event{})
+	assert.Error(t, err)
+
+	stdout, stderr, truncated := report.Output()
+	assert.Equal(t, "out\n", stdout)
+	assert.Equal(t, "err\n", stderr)
+	assert.False(t, truncated)
+}
+
+func TestReportTruncated(t *testing.T) {
+	report := NewReport(5)
+	stdout, stderr := outputWriters(WithReport(context.Background(), report))
+
+	stdout.Write([]byte("abc"))
+	stdout.Write([]byte("defg"))
+	stderr.Write([]byte("err"))
+
+	out, err, truncated := report.Output()
+	assert.Equal(t, "abcde", out)
+	assert.Equal(t, "err", err)
+	assert.True(t, truncated)
+}
+
+// streamLog records the writes to a log
+type streamLog struct {
+	writes []string
+}
+
+func (log *streamLog) WriteStream(stream string, p []byte) {
+	log.writes = append(log.writes, stream+":"+string(p))
+}
+
+func TestReportLog(t *testing.T) {
+	log := &streamLog{}
+	report := NewReport(2)
+	report.SetLog(log)
+	stdout, stderr := outputWriters(WithReport(context.Background(), report))
+
+	stdout.Write([]byte("out"))
+	stderr.Write([]byte("err"))
+	reportRequest(WithReport(context.Background(), report), "GET %s", "/")
+
+	//The log is not limited
+	assert.Equal(t, []string{"stdout:out", "stderr:err", "http:GET /\n"}, log.writes)
+}
+
+func TestStderrTail(t *testing.T) {
+	report := NewReport(0)
+	_, stderr := outputWriters(WithReport(context.Background(), report))
+
+	assert.Equal(t, "", report.StderrTail())
+
+	for i := 0; i < 100; i++ {
+		stderr.Write([]byte("a line of output\n"))
+	}
+	stderr.Write([]byte("the cause\n"))
+
+	tail := report.StderrTail()
+	assert.LessOrEqual(t, len(tail), tailSize)
+	assert.True(t, strings.HasPrefix(tail, "a line of output\n"))
+	assert.True(t, strings.HasSuffix(tail, "\nthe cause"))
+}
+
+func TestReportMissing(t *testing.T) {
+	//Executors run without a report, e.g. in tests, must not panic
+	stdout, _ := outputWriters(context.Background())
+	stdout.Write([]byte("out"))
+	reportStatus(context.Background(), 200)
+	reportRequest(context.Background(), "GET %s", "/")
+}
@@ -0,0 +1,149 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+)
+
+// Retry decorates an executor so that failed executions are retried with
+// backoff, according to config.Retry. Each failed attempt is logged
+// with its attempt number.
+//
+// The backoff is waited out by the caller, which for a job is a worker of
+// its pool, so the time spent retrying is bounded by MaxElapsed
+type Retry struct {
+	logger   logrus.FieldLogger
+	executor Executor
+	policy   config.Retry
+
+	randMu sync.Mutex
+	rand   *rand.Rand
+}
+
+// NewRetry decorates executor with the retry policy,
+// zero values of the policy are replaced by the defaults
+func NewRetry(logger logrus.FieldLogger, executor Executor, policy config.Retry) *Retry {
+	return &Retry{
+		logger:   logger,
+		executor: executor,
+		policy:   policy.Defaults(),
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// CloseIdleConnections closes the idle connections of the decorated executor
+func (retry *Retry) CloseIdleConnections() {
+	CloseIdleConnections(retry.executor)
+}
+
+// Execute runs the executor until it succeeds, returns an error that is not
+// retryable or has been attempted the maximum number of times. The error of
+// the last attempt is returned. Retrying stops if ctx is cancelled, or the
+// next delay would exceed MaxElapsed
+func (retry *Retry) Execute(ctx context.Context, ev event.Event) error {
+	started := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		err := retry.executor.Execute(ctx, ev)
+
+		logger := retry.logger.
+			WithField("svc", ev.Service).
+			WithField("attempt", attempt).
+			WithField("max_attempts", retry.policy.MaxAttempts)
+
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("Execution succeeded after retrying")
+			}
+			return nil
+		}
+
+		if attempt >= retry.policy.MaxAttempts || !retry.retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := retry.backoff(attempt)
+
+		if time.Since(started)+delay > retry.policy.MaxElapsed {
+			logger.
+				WithError(err).
+				WithField("max_elapsed", retry.policy.MaxElapsed.String()).
+				Warn("Execution attempt failed, max_elapsed reached")
+			return err
+		}
+
+		logger.
+			WithError(err).
+			WithField("delay", delay.String()).
+			Warn("Execution attempt failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff is the delay before the attempt following attempt
+func (retry *Retry) backoff(attempt int) time.Duration {
+	delay := float64(retry.policy.InitialBackoff) * math.Pow(retry.policy.Multiplier, float64(attempt-1))
+	delay = math.Min(delay, float64(retry.policy.MaxBackoff))
+
+	if retry.policy.Jitter > 0 {
+		retry.randMu.Lock()
+		delay -= delay * retry.policy.Jitter * retry.rand.Float64()
+		retry.randMu.Unlock()
+	}
+
+	return time.Duration(delay)
+}
+
+// retryable reports whether err is selected by the policy
+func (retry *Retry) retryable(err error) bool {
+	on := retry.policy.On
+	if on.IsZero() {
+		return true
+	}
+
+	if on.Timeout && (errors.Is(err, ErrTimeoutExceeded) || errors.Is(err, context.DeadlineExceeded)) {
+		return true
+	}
+
+	if len(on.ExitCodes) > 0 && hasExitCode(err) {
+		code := ExitCode(err)
+		for _, retryable := range on.ExitCodes {
+			if code == retryable {
+				return true
+			}
+		}
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		for _, status := range on.HttpStatus {
+			if status.Contains(statusErr.Code) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasExitCode reports whether err is the exit of a command,
+// rather than a failure to run it
+func hasExitCode(err error) bool {
+	var execErr *ExitError
+	var exitErr *exec.ExitError
+	return errors.As(err, &execErr) || errors.As(err, &exitErr)
+}
@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// failing constructs an executor that returns each of errs in turn,
+// then succeeds, counting its attempts
+func failing(attempts *int, errs ...error) Executor {
+	return ExecutorFunc(func(ctx context.Context, ev event.Event) error {
+		*attempts++
+		if *attempts <= len(errs) {
+			return errs[*attempts-1]
+		}
+		return nil
+	})
+}
+
+func TestRetryUntilSuccess(t *testing.T) {
+	attempts := 0
+	retry := NewRetry(logrus.New(), failing(&attempts, errors.New("a"), errors.New("b")), config.Retry{
+		InitialBackoff: time.Millisecond,
+	})
+
+	assert.NoError(t, retry.Execute(context.Background(), event.Event{}))
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	attempts := 0
+	last := errors.New("last")
+	retry := NewRetry(logrus.New(), failing(&attempts, errors.New("first"), last, errors.New("never")), config.Retry{
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+	})
+
+	assert.Equal(t, last, retry.Execute(context.Background(), event.Event{}))
+	assert.Equal(t, 2, attempts)
+}
+
+func TestRetryOn(t *testing.T) {
+	policy := config.Retry{
+		InitialBackoff: time.Millisecond,
+		On: config.RetryOn{
+			Timeout:    true,
+			ExitCodes:  []int{75},
+			HttpStatus: []config.StatusRange{"5xx"},
+		},
+	}
+
+	for _, err := range []error{
+		ErrTimeoutExceeded,
+		&ExitError{Code: 75},
+		&StatusError{Code: 503},
+	} {
+		attempts := 0
+		retry := NewRetry(logrus.New(), failing(&attempts, err), policy)
+
+		assert.NoError(t, retry.Execute(context.Background(), event.Event{}), err)
+		assert.Equal(t, 2, attempts, err)
+	}
+
+	for _, err := range []error{
+		errors.New("other"),
+		&ExitError{Code: 1},
+		&StatusError{Code: 404},
+	} {
+		attempts := 0
+		retry := NewRetry(logrus.New(), failing(&attempts, err), policy)
+
+		assert.Equal(t, err, retry.Execute(context.Background(), event.Event{}))
+		assert.Equal(t, 1, attempts, err)
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	attempts := 0
+	retry := NewRetry(logrus.New(), ExecutorFunc(func(ctx context.Context, ev event.Event) error {
+		attempts++
+		cancel()
+		return errors.New("failed")
+	}), config.Retry{InitialBackoff: time.Hour})
+
+	assert.Error(t, retry.Execute(ctx, event.Event{}))
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	retry := NewRetry(logrus.New(), nil, config.Retry{
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+	})
+
+	assert.Equal(t, time.Second, retry.backoff(1))
+	assert.Equal(t, 2*time.Second, retry.backoff(2))
+	assert.Equal(t, 4*time.Second, retry.backoff(3))
+	assert.Equal(t, 5*time.Second, retry.backoff(4))
+}
+
+func TestRetryMaxElapsed(t *testing.T) {
+	attempts := 0
+	first := errors.New("first")
+	retry := NewRetry(logrus.New(), failing(&attempts, first, errors.New("never")), config.Retry{
+		InitialBackoff: time.Second,
+		MaxElapsed:     500 * time.Millisecond,
+	})
+
+	assert.Equal(t, first, retry.Execute(context.Background(), event.Event{}))
+	assert.Equal(t, 1, attempts)
+}
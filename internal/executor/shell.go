@@ -1,24 +1,31 @@
 package executor
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"os"
 	"os/exec"
-	"strings"
 	"time"
 
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
 	"github.com/sirupsen/logrus"
 )
 
 // NewShell creates a new Shell based executor with default
 // values set for optional fields in the configuration and all
 // dependencies
-func NewShell(logger logrus.FieldLogger) *Shell {
+func NewShell(logger logrus.FieldLogger, templates *Templates) *Shell {
 	return &Shell{
 		logger:    logger,
+		templates: templates,
 		Timeout:   5,
 		LogOutput: false,
+		MaxOutput: DefaultMaxOutput,
+		KillGrace: DefaultKillGrace,
 	}
 }
 
@@ -29,20 +36,71 @@ func NewShell(logger logrus.FieldLogger) *Shell {
 // Defaults:
 // - Logging output is disabled
 // - Timeout for commands is 5s
+// - Output captured is limited to 1MiB
+// - Commands have 5s to exit after SIGTERM before they are killed
+//
+// If Stream is set, each line of stdout and stderr is logged as it is
+// written, rather than stdout once the command exits. At most MaxOutput
+// bytes of output are captured, in total, the rest is discarded.
+//
+// The command is run in its own process group. On timeout or cancellation
+// the group is sent SIGTERM, then SIGKILL if it has not exited within
+// KillGrace, see runGroup.
+//
+// Limits restricts the resources of the command and its children, see Limits.
+//
+// Command is rendered as a template, see Templates
 type Shell struct {
-	logger logrus.FieldLogger
+	logger    logrus.FieldLogger
+	templates *Templates
+
+	LogOutput bool            `yaml:"log"`
+	Stream    bool            `yaml:"stream"`
+	MaxOutput config.ByteSize `yaml:"max_output"`
+	Shell     string          `yaml:"shell"`
+	Command   string          `yaml:"command"`
+	Timeout   int             `yaml:"timeout"`
+	KillGrace time.Duration   `yaml:"kill_grace"`
+	Limits    *Limits         `yaml:"limits"`
+}
+
+// Validate checks that a command is provided and the timeout is usable
+func (shell *Shell) Validate() []error {
+	var errs []error
+
+	if shell.Command == "" {
+		errs = append(errs, config.Required("command"))
+	} else if err := shell.templates.Check("command", shell.Command); err != nil {
+		errs = append(errs, config.Invalid("command", "is not a valid template: %v", err))
+	}
+
+	if shell.Timeout <= 0 {
+		errs = append(errs, config.Invalid("timeout", "must be a positive number of seconds"))
+	}
 
-	LogOutput bool   `yaml:"log"`
-	Shell     string `yaml:"shell"`
-	Command   string `yaml:"command"`
-	Timeout   int    `yaml:"timeout"`
+	if shell.KillGrace < 0 {
+		errs = append(errs, config.Invalid("kill_grace", "must not be negative"))
+	}
+
+	if shell.MaxOutput <= 0 {
+		errs = append(errs, config.Invalid("max_output", "must be a positive size"))
+	}
+
+	errs = validateLimits(errs, shell.Limits)
+
+	return errs
 }
 
 // getShell determines the shell to use for execution of the specified
 // command. This is determined either by user configuration or environment variables.
 func (shell *Shell) getShell() string {
-	if shell.Shell != "" {
-		return shell.Shell
+	return userShell(shell.Shell)
+}
+
+// userShell returns the configured shell, if any, otherwise the shell of the user
+func userShell(configured string) string {
+	if configured != "" {
+		return configured
 	}
 
 	s, exists := os.LookupEnv("SHELL")
@@ -52,34 +110,91 @@ func (shell *Shell) getShell() string {
 	return s
 }
 
-// escape simply removes all \ characters from the string
-func escape(input string) string {
-	return strings.Replace(input, "\"", "", -1)
-}
-
 // Execute runs command defined by Shell, using configuration that is provided
 // by members of the defining struct
 // ctx is used to propagate any cancellation instructions of the command from the caller
-func (shell *Shell) Execute(ctx context.Context) error {
+//
+// The event is available to the command as SAUCISSON_* environment variables,
+// see event.Event.Env, and as JSON on stdin
+func (shell *Shell) Execute(ctx context.Context, ev event.Event) error {
 	ctx, done := context.WithTimeout(ctx, time.Second*time.Duration(shell.Timeout))
 	defer done()
 
 	sh := shell.getShell()
 
-	out, err := exec.CommandContext(ctx, sh, "-c", escape(shell.Command)).Output()
+	command, err := shell.templates.Render("command", shell.Command, ev)
+	if err != nil {
+		return err
+	}
+
+	stdin, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(sh, "-c", command)
+	cmd.Env = append(os.Environ(), ev.Env()...)
+	cmd.Stdin = bytes.NewReader(stdin)
+
+	err = shell.Limits.wrap(cmd)
+	if err != nil {
+		return err
+	}
+
+	//The limit is shared by everything that holds or logs the output, the
+	//tail of stderr is kept in full to explain failures
+	limit := newOutputLimit(int(shell.MaxOutput))
+	stdout := &bytes.Buffer{}
+	stderrTail := &tailBuffer{}
+	reportStdout, reportStderr := outputWriters(ctx)
+
+	logger := shell.logger.
+		WithField("svc", ev.Service).
+		WithField("job", jobID(ctx))
+
+	var stdoutLines, stderrLines *lineLogger
+	if shell.Stream {
+		stdoutLines = newLineLogger(logger, Stdout)
+		stderrLines = newLineLogger(logger, Stderr)
+		reportStdout = io.MultiWriter(stdoutLines, reportStdout)
+		reportStderr = io.MultiWriter(stderrLines, reportStderr)
+	} else {
+		reportStdout = io.MultiWriter(stdout, reportStdout)
+	}
+	cmd.Stdout = &limitedWriter{Writer: reportStdout, limit: limit}
+	cmd.Stderr = io.MultiWriter(stderrTail, &limitedWriter{Writer: reportStderr, limit: limit})
+
+	err = runGroup(ctx, logger, cmd, shell.KillGrace)
+
+	if shell.Stream {
+		stdoutLines.Flush()
+		stderrLines.Flush()
+	}
+
+	if limit.Exceeded() {
+		logger.
+			WithField("max_output", shell.MaxOutput).
+			Warn("Shell output exceeded max_output, the rest was discarded")
+	}
 
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err) {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) || os.IsTimeout(err) {
 			return ErrTimeoutExceeded
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if limit, possible := shell.Limits.exceeded(err, stderrTail.String()); limit != "" {
+			return &LimitError{Limit: limit, Possible: possible, Err: err}
+		}
 		return err
 	}
 
-	if shell.LogOutput {
-		shell.logger.
-			WithField("stdout", escape(string(out))).
+	if shell.LogOutput && !shell.Stream {
+		logger.
+			WithField("stdout", stdout.String()).
 			WithField("shell", sh).
-			WithField("input", escape(shell.Command)).
+			WithField("input", command).
 			Info("Shell execution output")
 	}
 
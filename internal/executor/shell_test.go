@@ -0,0 +1,114 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellEventEnvironment(t *testing.T) {
+	shell := NewShell(logrus.New(), nil)
+	shell.Shell = "sh"
+	shell.Command = `test "$SAUCISSON_PATH" = /tmp/file.txt && test "$SAUCISSON_CONDITION" = file`
+
+	err := shell.Execute(context.Background(), event.Event{
+		Condition: config.FileKey,
+		Path:      "/tmp/file.txt",
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestShellEventStdin(t *testing.T) {
+	shell := NewShell(logrus.New(), nil)
+	shell.Shell = "sh"
+	shell.Command = "grep -q 'executable.:.top'"
+
+	err := shell.Execute(context.Background(), event.Event{
+		Condition:  config.Processkey,
+		Executable: "top",
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestShellQuotes(t *testing.T) {
+	shell := NewShell(logrus.New(), NewTemplates(nil))
+	shell.Shell = "sh"
+	shell.Command = `test {{ json .Event.Path }} = "/tmp/a file.txt" && test "{{ .Event.Path }}" = "/tmp/a file.txt"`
+
+	err := shell.Execute(context.Background(), event.Event{Path: "/tmp/a file.txt"})
+
+	assert.NoError(t, err)
+}
+
+func TestShellExitCode(t *testing.T) {
+	shell := NewShell(logrus.New(), nil)
+	shell.Shell = "sh"
+	shell.Command = "exit 3"
+
+	err := shell.Execute(context.Background(), event.Event{})
+
+	assert.Equal(t, 3, ExitCode(err))
+}
+
+func TestShellTimeout(t *testing.T) {
+	shell := NewShell(logrus.New(), nil)
+	shell.Shell = "sh"
+	shell.Command = "exec sleep 5"
+	shell.Timeout = 1
+
+	err := shell.Execute(context.Background(), event.Event{})
+
+	assert.ErrorIs(t, err, ErrTimeoutExceeded)
+}
+
+func TestShellStream(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	shell := NewShell(logger, nil)
+	shell.Shell = "sh"
+	shell.Stream = true
+	shell.Command = "echo one; echo two >&2; printf three"
+
+	ctx := WithJobID(context.Background(), "job")
+	err := shell.Execute(ctx, event.Event{Service: "svc"})
+	assert.NoError(t, err)
+
+	lines := make(map[string][]string)
+	for _, entry := range hook.AllEntries() {
+		assert.Equal(t, "svc", entry.Data["svc"])
+		assert.Equal(t, "job", entry.Data["job"])
+		stream := entry.Data["stream"].(string)
+		lines[stream] = append(lines[stream], entry.Message)
+	}
+	assert.Equal(t, []string{"one", "three"}, lines[Stdout])
+	assert.Equal(t, []string{"two"}, lines[Stderr])
+}
+
+func TestShellMaxOutput(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	shell := NewShell(logger, nil)
+	shell.Shell = "sh"
+	shell.Stream = true
+	shell.MaxOutput = 10
+	shell.Command = "echo 12345; echo 67890; echo discarded"
+
+	report := NewReport(1024)
+	err := shell.Execute(WithReport(context.Background(), report), event.Event{})
+	assert.NoError(t, err)
+
+	entries := hook.AllEntries()
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "12345", entries[0].Message)
+	assert.Equal(t, "6789", entries[1].Message)
+	assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+
+	//The report is subject to the same limit
+	stdout, _, _ := report.Output()
+	assert.Equal(t, "12345\n6789", stdout)
+}
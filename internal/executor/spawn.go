@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"os/exec"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+)
+
+// NewSpawn creates a new Spawn executor with its dependencies,
+// processes are tracked by the provided Processes
+func NewSpawn(logger logrus.FieldLogger, templates *Templates, processes *Processes) *Spawn {
+	return &Spawn{
+		logger:    logger,
+		templates: templates,
+		processes: processes,
+	}
+}
+
+// Spawn starts a long-running process, such as an application or server,
+// without waiting for it to exit. The process is started in its own session
+// and process group, so it is unaffected by signals sent to saucisson.
+//
+// Stdin is detached and stdout and stderr are appended to Output if set,
+// otherwise they are discarded. The event is available to the command as
+// SAUCISSON_* environment variables, see event.Event.Env.
+//
+// If SingleInstance is set the process is not spawned while the previous
+// process of the service is still running. If StopOnShutdown is set the
+// process group is sent SIGTERM when saucisson exits.
+//
+// Command is rendered as a template, see Templates
+type Spawn struct {
+	logger    logrus.FieldLogger
+	templates *Templates
+	processes *Processes
+
+	Shell          string `yaml:"shell"`
+	Command        string `yaml:"command"`
+	Output         string `yaml:"output"`
+	SingleInstance bool   `yaml:"single_instance"`
+	StopOnShutdown bool   `yaml:"stop_on_shutdown"`
+}
+
+// Validate checks that a command is provided
+func (spawn *Spawn) Validate() []error {
+	var errs []error
+
+	if spawn.Command == "" {
+		errs = append(errs, config.Required("command"))
+	} else if err := spawn.templates.Check("command", spawn.Command); err != nil {
+		errs = append(errs, config.Invalid("command", "is not a valid template: %v", err))
+	}
+
+	return errs
+}
+
+// Execute starts the command and returns once it has started,
+// the process is not stopped if ctx is cancelled
+func (spawn *Spawn) Execute(ctx context.Context, ev event.Event) error {
+	sh := userShell(spawn.Shell)
+
+	command, err := spawn.templates.Render("command", spawn.Command, ev)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(sh, "-c", command)
+	cmd.Env = append(os.Environ(), ev.Env()...)
+	detach(cmd)
+
+	if spawn.Output != "" {
+		output, err := os.OpenFile(spawn.Output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		//The child has its own copy of the descriptor once started
+		defer output.Close()
+
+		cmd.Stdout = output
+		cmd.Stderr = output
+	}
+
+	pid, started, err := spawn.processes.start(ev.Service, cmd, spawn.SingleInstance, spawn.StopOnShutdown)
+	if err != nil {
+		return err
+	}
+
+	logger := spawn.logger.
+		WithField("svc", ev.Service).
+		WithField("pid", pid)
+
+	if !started {
+		logger.Info("Previous process is still running, not spawning")
+		return nil
+	}
+
+	logger.
+		WithField("command", command).
+		Info("Process spawned")
+
+	return nil
+}
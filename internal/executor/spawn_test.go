@@ -0,0 +1,72 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpawnOutput(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "spawn.log")
+
+	spawn := NewSpawn(logrus.New(), nil, NewProcesses(logrus.New()))
+	spawn.Shell = "sh"
+	spawn.Command = `echo "$SAUCISSON_CONDITION"`
+	spawn.Output = output
+
+	err := spawn.Execute(context.Background(), event.Event{Service: "svc", Condition: config.CronKey})
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		out, _ := os.ReadFile(output)
+		return string(out) == "cron\n"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSpawnSingleInstance(t *testing.T) {
+	processes := NewProcesses(logrus.New())
+
+	spawn := NewSpawn(logrus.New(), nil, processes)
+	spawn.Shell = "sh"
+	spawn.Command = "sleep 5"
+	spawn.SingleInstance = true
+	spawn.StopOnShutdown = true
+	defer processes.Stop(context.Background())
+
+	ev := event.Event{Service: "svc"}
+
+	assert.NoError(t, spawn.Execute(context.Background(), ev))
+	assert.NoError(t, spawn.Execute(context.Background(), ev))
+
+	processes.mu.Lock()
+	assert.Len(t, processes.procs, 1)
+	processes.mu.Unlock()
+}
+
+func TestProcessesStop(t *testing.T) {
+	processes := NewProcesses(logrus.New())
+
+	spawn := NewSpawn(logrus.New(), nil, processes)
+	spawn.Shell = "sh"
+	//The child of the shell is in the same process group so is also stopped
+	spawn.Command = "sleep 30; sleep 30"
+	spawn.StopOnShutdown = true
+
+	assert.NoError(t, spawn.Execute(context.Background(), event.Event{Service: "svc"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.NoError(t, processes.Stop(ctx))
+
+	processes.mu.Lock()
+	assert.Empty(t, processes.procs)
+	processes.mu.Unlock()
+}
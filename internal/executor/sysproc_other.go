@@ -0,0 +1,35 @@
+//go:build !unix
+
+package executor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// detach is a no-op where sessions are not supported
+func detach(cmd *exec.Cmd) {}
+
+// setProcessGroup is a no-op where process groups are not supported
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// setCredential is unsupported where credentials cannot be set
+func setCredential(cmd *exec.Cmd, uid, gid uint32) error {
+	return errors.New("user and group are not supported on this platform")
+}
+
+// signalGroup signals only the process itself where process groups are
+// not supported, any signal other than SIGKILL is delivered as os.Interrupt
+func signalGroup(pid int, sig syscall.Signal) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	if sig == syscall.SIGKILL {
+		return process.Kill()
+	}
+	return process.Signal(os.Interrupt)
+}
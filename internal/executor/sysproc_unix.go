@@ -0,0 +1,46 @@
+//go:build unix
+
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach starts cmd in a new session, and so a new process group,
+// so that it does not receive signals sent to saucisson's process group
+func detach(cmd *exec.Cmd) {
+	sysProcAttr(cmd).Setsid = true
+}
+
+// setProcessGroup starts cmd in a new process group, so that it
+// can be signalled along with its children, see signalGroup
+func setProcessGroup(cmd *exec.Cmd) {
+	if !sysProcAttr(cmd).Setsid {
+		sysProcAttr(cmd).Setpgid = true
+	}
+}
+
+// setCredential runs cmd as the provided user and group,
+// without any supplementary groups
+func setCredential(cmd *exec.Cmd, uid, gid uint32) error {
+	sysProcAttr(cmd).Credential = &syscall.Credential{
+		Uid:    uid,
+		Gid:    gid,
+		Groups: []uint32{},
+	}
+	return nil
+}
+
+// sysProcAttr returns the attributes of cmd, creating them if necessary
+func sysProcAttr(cmd *exec.Cmd) *syscall.SysProcAttr {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	return cmd.SysProcAttr
+}
+
+// signalGroup sends sig to every process in the process group led by pid
+func signalGroup(pid int, sig syscall.Signal) error {
+	return syscall.Kill(-pid, sig)
+}
@@ -0,0 +1,224 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/event"
+)
+
+// Templates renders the string fields of executors, e.g. a shell command or
+// a HTTP body, as text/template templates at execution time.
+//
+// Templates are provided with TemplateData and the helper functions:
+//
+//	basename, dirname, json, quote, now, env
+//
+// A nil *Templates renders templates without any vars
+type Templates struct {
+	vars map[string]any
+}
+
+// NewTemplates constructs Templates with the top-level vars of the config
+func NewTemplates(vars map[string]any) *Templates {
+	return &Templates{
+		vars: vars,
+	}
+}
+
+// TemplateData is the data available to templates
+type TemplateData struct {
+	// Service is the name of the service being executed
+	Service string
+	// Event is the event that satisfied the condition of the service
+	Event event.Event
+	// Vars are the top-level vars of the config
+	Vars map[string]any
+}
+
+var templateFuncs = template.FuncMap{
+	"basename": filepath.Base,
+	"dirname":  filepath.Dir,
+	"json": func(v any) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+	"quote": quote,
+	"now":   time.Now,
+	"env":   os.Getenv,
+}
+
+// quote single quotes s so that it is interpreted literally by a POSIX shell
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Render renders text as a template with the provided event.
+// Referencing a var that does not exist is an error, whereas the keys of
+// the maps of the event, such as headers, are empty when they are missing
+func (templates *Templates) Render(name, text string, ev event.Event) (string, error) {
+	// Most fields are plain strings and need no parsing
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New(name).
+		Funcs(templateFuncs).
+		Parse(text)
+
+	if err != nil {
+		return "", err
+	}
+
+	data := TemplateData{
+		Service: ev.Service,
+		Event:   ev,
+	}
+
+	if templates != nil {
+		data.Vars = templates.vars
+	}
+
+	err = checkVars(tmpl.Tree, tmpl.Tree.Root, data.Vars, true)
+	if err != nil {
+		return "", err
+	}
+
+	out := &strings.Builder{}
+	err = tmpl.Execute(out, data)
+	if err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+// checkVars returns an error if node refers to a var that does not exist.
+// Within the body of range and with the dot is no longer TemplateData, so
+// only references through $ are checked there
+func checkVars(tree *parse.Tree, node parse.Node, vars map[string]any, top bool) error {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return nil
+		}
+		for _, child := range node.Nodes {
+			if err := checkVars(tree, child, vars, top); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkVars(tree, node.Pipe, vars, top)
+	case *parse.TemplateNode:
+		return checkVars(tree, node.Pipe, vars, top)
+	case *parse.IfNode:
+		return checkBranch(tree, &node.BranchNode, vars, top, top)
+	case *parse.RangeNode:
+		return checkBranch(tree, &node.BranchNode, vars, top, false)
+	case *parse.WithNode:
+		return checkBranch(tree, &node.BranchNode, vars, top, false)
+	case *parse.PipeNode:
+		if node == nil {
+			return nil
+		}
+		for _, cmd := range node.Cmds {
+			if err := checkVars(tree, cmd, vars, top); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range node.Args {
+			if err := checkVars(tree, arg, vars, top); err != nil {
+				return err
+			}
+		}
+	case *parse.ChainNode:
+		return checkVars(tree, node.Node, vars, top)
+	case *parse.FieldNode:
+		if top {
+			return lookupVar(tree, node, node.Ident, vars)
+		}
+	case *parse.VariableNode:
+		if node.Ident[0] == "$" {
+			return lookupVar(tree, node, node.Ident[1:], vars)
+		}
+	}
+
+	return nil
+}
+
+// checkBranch checks the pipeline and bodies of an if, range or with, body
+// is whether the dot of its body is still TemplateData
+func checkBranch(tree *parse.Tree, node *parse.BranchNode, vars map[string]any, top bool, body bool) error {
+	if err := checkVars(tree, node.Pipe, vars, top); err != nil {
+		return err
+	}
+	if err := checkVars(tree, node.List, vars, body); err != nil {
+		return err
+	}
+	return checkVars(tree, node.ElseList, vars, top)
+}
+
+// lookupVar returns an error if the fields of TemplateData in ident refer to
+// a var that does not exist. Values other than maps are not looked into
+func lookupVar(tree *parse.Tree, node parse.Node, ident []string, vars map[string]any) error {
+	if len(ident) < 2 || ident[0] != "Vars" {
+		return nil
+	}
+
+	var value any = vars
+	for i, key := range ident[1:] {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		value, ok = m[key]
+		if !ok {
+			location, _ := tree.ErrorContext(node)
+			return fmt.Errorf("template: %s: var %s is not defined", location, strings.Join(ident[1:i+2], "."))
+		}
+	}
+
+	return nil
+}
+
+// RenderMap renders every value of m, returning a new map
+func (templates *Templates) RenderMap(name string, m map[string]string, ev event.Event) (map[string]string, error) {
+	rendered := make(map[string]string, len(m))
+
+	for k, v := range m {
+		value, err := templates.Render(name+"."+k, v, ev)
+		if err != nil {
+			return nil, err
+		}
+		rendered[k] = value
+	}
+
+	return rendered, nil
+}
+
+// Check renders text against a sample event so that errors in the template,
+// such as syntax errors or unknown vars, are found when the config is loaded
+// rather than when it is executed
+func (templates *Templates) Check(name, text string) error {
+	_, err := templates.Render(name, text, checkEvent())
+	return err
+}
+
+// checkEvent is the sample event that templates are checked against
+func checkEvent() event.Event {
+	now := time.Now()
+
+	return event.Event{
+		Service:   "check",
+		Time:      now,
+		Scheduled: &now,
+	}
+}
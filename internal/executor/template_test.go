@@ -0,0 +1,67 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderEvent(t *testing.T) {
+	templates := NewTemplates(map[string]any{"dest": "/backup"})
+
+	out, err := templates.Render("command", "cp {{ .Event.Path | quote }} {{ .Vars.dest }}/{{ basename .Event.Path }}", event.Event{
+		Path: "/tmp/it's.txt",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `cp '/tmp/it'\''s.txt' /backup/it's.txt`, out)
+}
+
+func TestRenderJson(t *testing.T) {
+	out, err := (*Templates)(nil).Render("body", `{"svc": {{ json .Service }}}`, event.Event{Service: "svc"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"svc": "svc"}`, out)
+}
+
+func TestCheck(t *testing.T) {
+	templates := NewTemplates(map[string]any{"dest": "/backup"})
+
+	assert.NoError(t, templates.Check("command", "echo {{ .Vars.dest }} {{ .Event.Scheduled.Unix }}"))
+	assert.Error(t, templates.Check("command", "echo {{ .Vars.missing }}"))
+	assert.Error(t, templates.Check("command", "echo {{ .Event.Missing }}"))
+	assert.Error(t, templates.Check("command", "echo {{ .Event.Path "))
+	assert.Error(t, templates.Check("command", "echo {{ unknown }}"))
+}
+
+func TestCheckVars(t *testing.T) {
+	templates := NewTemplates(map[string]any{
+		"backup": map[string]any{"dest": "/backup"},
+		"hosts":  []any{"a", "b"},
+	})
+
+	assert.NoError(t, templates.Check("command", "echo {{ .Vars.backup.dest }}"))
+	assert.NoError(t, templates.Check("command", "{{ range .Vars.hosts }}{{ . }} {{ $.Vars.backup.dest }}{{ end }}"))
+	assert.NoError(t, templates.Check("command", "{{ with .Vars.backup }}{{ .dest }}{{ end }}"))
+
+	err := templates.Check("command", "echo {{ .Vars.backup.missing }}")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "var backup.missing is not defined")
+	}
+	assert.Error(t, templates.Check("command", "{{ if true }}{{ .Vars.missing }}{{ end }}"))
+	assert.Error(t, templates.Check("command", "{{ range .Vars.hosts }}{{ $.Vars.missing }}{{ end }}"))
+	assert.Error(t, templates.Check("command", "{{ .Vars.missing | quote }}"))
+	assert.Error(t, (*Templates)(nil).Check("command", "{{ .Vars.missing }}"))
+}
+
+func TestRenderMissingEventKeys(t *testing.T) {
+	templates := NewTemplates(nil)
+
+	//Requests need not have every header or query parameter
+	assert.NoError(t, templates.Check("url", `{{ .Event.Query.ref }} {{ .Event.Headers.X }} {{ .Event.Query.Get "ref" }}`))
+
+	out, err := templates.Render("url", `ref={{ .Event.Query.Get "ref" }}&id={{ .Event.Headers.Get "X-Id" }}`, event.Event{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ref=&id=", out)
+}
@@ -0,0 +1,181 @@
+package history
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/mickyco94/saucisson/internal/executor"
+)
+
+// FileName is the name of the history file within its directory
+const FileName = "history.jsonl"
+
+// Result is the outcome of an execution
+type Result string
+
+const (
+	Success Result = "success"
+	Failure Result = "failure"
+	// Timeout executions exceeded the timeout of their executor
+	Timeout Result = "timeout"
+	// Panic executions failed because the executor panicked
+	Panic Result = "panic"
+	// Cancelled executions were stopped before they completed,
+	// e.g. replaced by a newer trigger or saucisson shutting down
+	Cancelled Result = "cancelled"
+)
+
+// ResultOf determines the result of an execution from its error
+func ResultOf(err error) Result {
+	var panicErr *executor.PanicError
+
+	switch {
+	case err == nil:
+		return Success
+	case errors.As(err, &panicErr):
+		return Panic
+	case errors.Is(err, executor.ErrTimeoutExceeded), errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	case errors.Is(err, executor.ErrReplaced), errors.Is(err, context.Canceled):
+		return Cancelled
+	default:
+		return Failure
+	}
+}
+
+// Entry is the record of a single execution of a service.
+// Event is redacted when the entry is appended, see Redact
+type Entry struct {
+	ID       string        `json:"id"`
+	Service  string        `json:"service"`
+	Event    event.Event   `json:"event"`
+	Started  time.Time     `json:"started"`
+	Finished time.Time     `json:"finished"`
+	Duration time.Duration `json:"duration"`
+	Result   Result        `json:"result"`
+	Error    string        `json:"error,omitempty"`
+	// Stdout and Stderr are the output of commands, truncated
+	// to the max_output of the history config
+	Stdout    string `json:"stdout,omitempty"`
+	Stderr    string `json:"stderr,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	// HttpStatus is the status of the last HTTP response, if any
+	HttpStatus int `json:"http_status,omitempty"`
+	// Termination is whether commands exited on their own, or were
+	// terminated or killed on timeout or cancellation. Empty if no
+	// command was run
+	Termination executor.Termination `json:"termination,omitempty"`
+	// Limit is the limit of the executor that stopped the command, if any
+	Limit string `json:"limit,omitempty"`
+}
+
+// Redact returns a copy of ev without the headers, query and body of a
+// webhook request, which can hold secrets and payloads that should not be
+// kept on disk. Events of a batch are redacted too
+func Redact(ev event.Event) event.Event {
+	ev.Headers = nil
+	ev.Query = nil
+	ev.Body = ""
+
+	if len(ev.Batch) > 0 {
+		batch := make([]event.Event, len(ev.Batch))
+		for i, batched := range ev.Batch {
+			batch[i] = Redact(batched)
+		}
+		ev.Batch = batch
+	}
+
+	return ev
+}
+
+// Filter selects entries from the history, zero values select everything
+type Filter struct {
+	Service string
+	// Failed selects executions that did not succeed
+	Failed bool
+	// Since selects executions that started at or after the time
+	Since time.Time
+}
+
+// Match reports whether entry is selected by the filter
+func (filter Filter) Match(entry Entry) bool {
+	if filter.Service != "" && entry.Service != filter.Service {
+		return false
+	}
+
+	if filter.Failed && entry.Result == Success {
+		return false
+	}
+
+	return !entry.Started.Before(filter.Since)
+}
+
+// DefaultDir returns $XDG_STATE_HOME/saucisson, falling back to
+// ~/.local/state/saucisson if XDG_STATE_HOME is not set
+func DefaultDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "saucisson")
+	}
+
+	return filepath.Join(os.Getenv("HOME"), ".local", "state", "saucisson")
+}
+
+// Read returns the entries of the history in dir that are selected by
+// filter, oldest first. A history that does not exist yet is empty
+func Read(dir string, filter Filter) ([]Entry, error) {
+	entries := make([]Entry, 0)
+
+	err := scan(filepath.Join(dir, FileName), func(entry Entry, _ []byte) {
+		if filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	})
+
+	return entries, err
+}
+
+// scan invokes fn with every entry of the history file at path, along with
+// the line it was decoded from. Lines that cannot be decoded, such as an entry
+// that is still being written, are skipped
+func scan(path string, fn func(entry Entry, line []byte)) error {
+	return scanLimited(path, -1, fn)
+}
+
+// scanLimited scans the first size bytes of the history file at path, or
+// all of it if size is negative, see scan
+func scanLimited(path string, size int64, fn func(entry Entry, line []byte)) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if size >= 0 {
+		reader = io.LimitReader(file, size)
+	}
+
+	scanner := bufio.NewScanner(reader)
+	//Entries can hold the output of commands and the body of a webhook request
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		fn(entry, scanner.Bytes())
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,171 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/mickyco94/saucisson/internal/executor"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// entry constructs an entry of service that finished at finished
+func entry(id string, service string, result Result, finished time.Time) Entry {
+	return Entry{
+		ID:       id,
+		Service:  service,
+		Started:  finished.Add(-time.Second),
+		Finished: finished,
+		Duration: time.Second,
+		Result:   result,
+	}
+}
+
+func TestAppendAndRead(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	store := NewStore(logrus.New(), config.History{Dir: dir})
+
+	now := time.Now()
+	assert.NoError(t, store.Append(entry("1", "a", Success, now.Add(-2*time.Hour))))
+	assert.NoError(t, store.Append(entry("2", "b", Failure, now.Add(-time.Minute))))
+	assert.NoError(t, store.Append(entry("3", "a", Cancelled, now)))
+
+	all, err := Read(dir, Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+	assert.Equal(t, "1", all[0].ID)
+
+	byService, err := Read(dir, Filter{Service: "a"})
+	assert.NoError(t, err)
+	assert.Len(t, byService, 2)
+
+	failed, err := Read(dir, Filter{Failed: true, Since: now.Add(-time.Hour)})
+	assert.NoError(t, err)
+	assert.Len(t, failed, 2)
+	assert.Equal(t, "2", failed[0].ID)
+	assert.Equal(t, "3", failed[1].ID)
+}
+
+func TestReadMissing(t *testing.T) {
+	entries, err := Read(filepath.Join(t.TempDir(), "missing"), Filter{})
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestReadSkipsPartialLine(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(logrus.New(), config.History{Dir: dir})
+	assert.NoError(t, store.Append(entry("1", "a", Success, time.Now())))
+
+	file, err := os.OpenFile(filepath.Join(dir, FileName), os.O_WRONLY|os.O_APPEND, 0600)
+	assert.NoError(t, err)
+	file.WriteString(`{"id": "2", "serv`)
+	file.Close()
+
+	entries, err := Read(dir, Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestDisabled(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	store := NewStore(logrus.New(), config.History{Dir: dir, Disabled: true})
+
+	assert.NoError(t, store.Append(entry("1", "a", Success, time.Now())))
+
+	_, err := os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRetentionSize(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(logrus.New(), config.History{Dir: dir, MaxSize: 1024})
+
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, store.Append(entry("id", "svc", Success, time.Now())))
+	}
+	store.Wait()
+
+	info, err := os.Stat(filepath.Join(dir, FileName))
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(1024))
+
+	entries, err := Read(dir, Filter{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, entries)
+	assert.Equal(t, info.Size(), store.size)
+}
+
+func TestRetentionAge(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(logrus.New(), config.History{Dir: dir, MaxAge: time.Hour})
+
+	now := time.Now()
+	assert.NoError(t, store.Append(entry("old", "svc", Success, now.Add(-2*time.Hour))))
+	assert.NoError(t, store.Append(entry("new", "svc", Success, now)))
+	store.Wait()
+
+	entries, err := Read(dir, Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "new", entries[0].ID)
+}
+
+func TestRetentionKeepsNewest(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(logrus.New(), config.History{Dir: dir, MaxSize: 1024})
+
+	large := entry("large", "svc", Failure, time.Now())
+	large.Stdout = strings.Repeat("x", 2048)
+
+	assert.NoError(t, store.Append(entry("small", "svc", Success, time.Now())))
+	assert.NoError(t, store.Append(large))
+	store.Wait()
+
+	entries, err := Read(dir, Filter{})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "large", entries[0].ID)
+	}
+}
+
+func TestAppendRedacts(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(logrus.New(), config.History{Dir: dir})
+
+	webhook := event.Event{
+		Path:    "/deploy",
+		Headers: http.Header{"Authorization": {"Bearer secret"}},
+		Query:   url.Values{"token": {"secret"}},
+		Body:    "payload",
+	}
+
+	appended := entry("1", "svc", Success, time.Now())
+	appended.Event = webhook
+	appended.Event.Batch = []event.Event{webhook}
+	assert.NoError(t, store.Append(appended))
+
+	content, err := os.ReadFile(filepath.Join(dir, FileName))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "secret")
+	assert.NotContains(t, string(content), "payload")
+	assert.Contains(t, string(content), "/deploy")
+}
+
+func TestResultOf(t *testing.T) {
+	assert.Equal(t, Success, ResultOf(nil))
+	assert.Equal(t, Failure, ResultOf(errors.New("failed")))
+	assert.Equal(t, Cancelled, ResultOf(executor.ErrReplaced))
+	assert.Equal(t, Cancelled, ResultOf(context.Canceled))
+	assert.Equal(t, Timeout, ResultOf(executor.ErrTimeoutExceeded))
+	assert.Equal(t, Panic, ResultOf(&executor.PanicError{Value: "oops"}))
+}
@@ -0,0 +1,243 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogsDir is the directory of the logs of jobs within the history directory
+const LogsDir = "logs"
+
+// logTimeFormat is the timestamp of each line of a job log
+const logTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// maxLineSize is the longest line written to a job log, longer lines are
+// split so that output without newlines is not held in memory
+const maxLineSize = 64 << 10
+
+// truncatedLine is written in place of the output beyond the max size of a job log
+const truncatedLine = "[output truncated, logs.max_size reached]"
+
+// LogDir returns the directory of the logs of service in the history in dir
+func LogDir(dir string, service string) string {
+	return filepath.Join(dir, LogsDir, escapeName(service))
+}
+
+// escapeName makes a service name usable as the name of a directory
+func escapeName(name string) string {
+	escaped := url.PathEscape(name)
+	if strings.HasPrefix(escaped, ".") {
+		escaped = "%2E" + escaped[1:]
+	}
+	return escaped
+}
+
+// LogFile is the log of a single job
+type LogFile struct {
+	ID       string
+	Path     string
+	Modified time.Time
+}
+
+// ListLogs returns the logs of service in the history in dir, oldest first.
+// A service without logs has none
+func ListLogs(dir string, service string) ([]LogFile, error) {
+	return listLogs(LogDir(dir, service))
+}
+
+// listLogs returns the logs in the directory of a service, oldest first
+func listLogs(dir string) ([]LogFile, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]LogFile, 0, len(entries))
+	for _, entry := range entries {
+		id := strings.TrimSuffix(entry.Name(), ".log")
+		if entry.IsDir() || id == entry.Name() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			//Removed since the directory was read
+			continue
+		}
+
+		logs = append(logs, LogFile{
+			ID:       id,
+			Path:     filepath.Join(dir, entry.Name()),
+			Modified: info.ModTime(),
+		})
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].Modified.Equal(logs[j].Modified) {
+			return logs[i].ID < logs[j].ID
+		}
+		return logs[i].Modified.Before(logs[j].Modified)
+	})
+
+	return logs, nil
+}
+
+// OpenLog creates the log of the job id of service, removing the logs of the
+// service that are beyond the retention of the config. Returns nil if the
+// history or job logs are disabled
+func (store *Store) OpenLog(service string, id string) (*JobLog, error) {
+	store.mu.Lock()
+	cfg := store.cfg
+	store.mu.Unlock()
+
+	if cfg.Disabled || cfg.Logs.Disabled {
+		return nil, nil
+	}
+
+	dir := LogDir(cfg.Dir, service)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := listLogs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := store.now().Add(-cfg.Logs.MaxAge)
+	//Leave room for the log being opened
+	excess := len(logs) - cfg.Logs.MaxFiles + 1
+
+	for i, log := range logs {
+		if i >= excess && !log.Modified.Before(cutoff) {
+			break
+		}
+		err := os.Remove(log.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, id+".log"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JobLog{
+		file:    file,
+		partial: make(map[string][]byte),
+		maxSize: int64(cfg.Logs.MaxSize),
+		now:     store.now,
+	}, nil
+}
+
+// JobLog writes the output of a job to its log file. Each line is prefixed
+// with the time it was written and its stream, so that the streams are
+// interleaved in the order they were written. Output beyond maxSize is
+// discarded
+type JobLog struct {
+	mu        sync.Mutex
+	file      *os.File
+	partial   map[string][]byte
+	maxSize   int64
+	written   int64
+	truncated bool
+	err       error
+
+	now func() time.Time
+}
+
+// WriteStream writes the complete lines of p to the log, the remainder is
+// written once the line is complete, reaches maxLineSize or the log is
+// closed. Errors are returned by Close
+func (log *JobLog) WriteStream(stream string, p []byte) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	for len(p) > 0 {
+		partial := log.partial[stream]
+
+		line, rest, complete := p, []byte(nil), false
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			line, rest, complete = p[:i], p[i+1:], true
+		}
+
+		if room := maxLineSize - len(partial); len(line) > room {
+			line, rest, complete = p[:room], p[room:], true
+		}
+
+		if !complete {
+			log.partial[stream] = append(partial, line...)
+			return
+		}
+
+		if len(partial) > 0 {
+			line = append(partial, line...)
+			log.partial[stream] = partial[:0]
+		}
+		log.writeLine(stream, line)
+		p = rest
+	}
+}
+
+// writeLine writes a single line of stream, or the truncation marker once
+// the log reaches maxSize. Must be called with mu held
+func (log *JobLog) writeLine(stream string, line []byte) {
+	if log.err != nil || log.truncated {
+		return
+	}
+
+	size := int64(len(logTimeFormat) + len(stream) + len(line) + 3)
+	if log.maxSize > 0 && log.written+size > log.maxSize {
+		log.truncated = true
+		line = []byte(truncatedLine)
+	}
+
+	buf := make([]byte, 0, len(logTimeFormat)+len(stream)+len(line)+3)
+	buf = log.now().AppendFormat(buf, logTimeFormat)
+	buf = append(buf, ' ')
+	buf = append(buf, stream...)
+	buf = append(buf, ' ')
+	buf = append(buf, line...)
+	buf = append(buf, '\n')
+
+	var n int
+	n, log.err = log.file.Write(buf)
+	log.written += int64(n)
+}
+
+// Close writes any incomplete lines and closes the log file
+func (log *JobLog) Close() error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	streams := make([]string, 0, len(log.partial))
+	for stream, data := range log.partial {
+		if len(data) > 0 {
+			streams = append(streams, stream)
+		}
+	}
+	sort.Strings(streams)
+
+	for _, stream := range streams {
+		log.writeLine(stream, log.partial[stream])
+	}
+	log.partial = nil
+
+	err := log.file.Close()
+	if log.err != nil {
+		return log.err
+	}
+	return err
+}
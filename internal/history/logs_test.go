@@ -0,0 +1,135 @@
+package history
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobLog(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(logrus.New(), config.History{Dir: dir})
+	store.now = func() time.Time {
+		return time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	}
+
+	log, err := store.OpenLog("svc", "job")
+	assert.NoError(t, err)
+
+	log.WriteStream("stdout", []byte("one\ntw"))
+	log.WriteStream("stderr", []byte("failed\n"))
+	log.WriteStream("stdout", []byte("o\nthree"))
+	assert.NoError(t, log.Close())
+
+	content, err := os.ReadFile(filepath.Join(LogDir(dir, "svc"), "job.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, `2024-01-02T03:04:05.006Z stdout one
+2024-01-02T03:04:05.006Z stderr failed
+2024-01-02T03:04:05.006Z stdout two
+2024-01-02T03:04:05.006Z stdout three
+`, string(content))
+
+	logs, err := ListLogs(dir, "svc")
+	assert.NoError(t, err)
+	assert.Len(t, logs, 1)
+	assert.Equal(t, "job", logs[0].ID)
+}
+
+func TestJobLogSplitsLongLines(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(logrus.New(), config.History{Dir: dir})
+
+	log, err := store.OpenLog("svc", "job")
+	assert.NoError(t, err)
+
+	chunk := bytes.Repeat([]byte("a"), maxLineSize/2+1)
+	log.WriteStream("stdout", chunk)
+	log.WriteStream("stdout", chunk)
+	log.WriteStream("stdout", []byte("\n"))
+	assert.NoError(t, log.Close())
+
+	content, err := os.ReadFile(filepath.Join(LogDir(dir, "svc"), "job.log"))
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.True(t, strings.HasSuffix(lines[0], " stdout "+strings.Repeat("a", maxLineSize)))
+		assert.True(t, strings.HasSuffix(lines[1], " stdout aa"))
+	}
+}
+
+func TestJobLogMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(logrus.New(), config.History{
+		Dir:  dir,
+		Logs: config.JobLogs{MaxSize: 100},
+	})
+	store.now = func() time.Time {
+		return time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	}
+
+	log, err := store.OpenLog("svc", "job")
+	assert.NoError(t, err)
+
+	log.WriteStream("stdout", []byte("one\ntwo\nthree\nfour\n"))
+	assert.NoError(t, log.Close())
+
+	content, err := os.ReadFile(filepath.Join(LogDir(dir, "svc"), "job.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, `2024-01-02T03:04:05.006Z stdout one
+2024-01-02T03:04:05.006Z stdout two
+2024-01-02T03:04:05.006Z stdout [output truncated, logs.max_size reached]
+`, string(content))
+}
+
+func TestJobLogRetention(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(logrus.New(), config.History{
+		Dir:  dir,
+		Logs: config.JobLogs{MaxFiles: 2, MaxAge: time.Hour},
+	})
+
+	open := func(id string, modified time.Time) {
+		log, err := store.OpenLog("svc", id)
+		assert.NoError(t, err)
+		assert.NoError(t, log.Close())
+		assert.NoError(t, os.Chtimes(filepath.Join(LogDir(dir, "svc"), id+".log"), modified, modified))
+	}
+
+	now := time.Now()
+	open("expired", now.Add(-2*time.Hour))
+	open("1", now.Add(-3*time.Minute))
+	open("2", now.Add(-2*time.Minute))
+	open("3", now.Add(-time.Minute))
+
+	logs, err := ListLogs(dir, "svc")
+	assert.NoError(t, err)
+	assert.Len(t, logs, 2)
+	assert.Equal(t, "2", logs[0].ID)
+	assert.Equal(t, "3", logs[1].ID)
+}
+
+func TestJobLogDisabled(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(logrus.New(), config.History{Dir: dir, Logs: config.JobLogs{Disabled: true}})
+
+	log, err := store.OpenLog("svc", "job")
+	assert.NoError(t, err)
+	assert.Nil(t, log)
+
+	logs, err := ListLogs(dir, "svc")
+	assert.NoError(t, err)
+	assert.Empty(t, logs)
+}
+
+func TestLogDirEscapesName(t *testing.T) {
+	assert.Equal(t, filepath.Join("state", LogsDir, "a%2Fb"), LogDir("state", "a/b"))
+	assert.Equal(t, filepath.Join("state", LogsDir, "%2E."), LogDir("state", ".."))
+}
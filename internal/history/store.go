@@ -0,0 +1,349 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// DefaultMaxSize is the size the history is trimmed to, unless configured otherwise
+	DefaultMaxSize = config.ByteSize(10 << 20)
+	// DefaultMaxAge is how long entries are kept, unless configured otherwise
+	DefaultMaxAge = 30 * 24 * time.Hour
+	// DefaultMaxOutput is the output kept for each execution, unless configured otherwise
+	DefaultMaxOutput = config.ByteSize(4 << 10)
+	// DefaultLogMaxAge is how long the logs of jobs are kept, unless configured otherwise
+	DefaultLogMaxAge = 7 * 24 * time.Hour
+	// DefaultLogMaxFiles is the number of job logs kept for each service, unless configured otherwise
+	DefaultLogMaxFiles = 100
+	// DefaultLogMaxSize is the size of the log of each job, unless configured otherwise
+	DefaultLogMaxSize = config.ByteSize(10 << 20)
+)
+
+// compactInterval is the minimum time between removing expired entries,
+// so that the history is not rewritten every time an entry expires
+var compactInterval = time.Hour
+
+// Store appends entries to the history file, removing old entries according
+// to the retention of the history config. Entries are appended as JSON lines,
+// so that the history can be read while saucisson is running, see Read
+type Store struct {
+	logger logrus.FieldLogger
+
+	mu  sync.Mutex
+	cfg config.History
+
+	//size and oldest describe the history file, they are
+	//read from the file when loaded is false
+	loaded    bool
+	size      int64
+	oldest    time.Time
+	compacted time.Time
+
+	//compacting is set while the history is compacted in the background
+	compacting  bool
+	compactions sync.WaitGroup
+
+	now func() time.Time
+}
+
+// NewStore constructs a store for the history config, nothing is
+// read or written until the first entry is appended
+func NewStore(logger logrus.FieldLogger, cfg config.History) *Store {
+	store := &Store{
+		logger: logger,
+		now:    time.Now, //Setting this here supports mocking
+	}
+	store.Configure(cfg)
+	return store
+}
+
+// Configure replaces the history config, zero values are replaced by the defaults
+func (store *Store) Configure(cfg config.History) {
+	if cfg.Dir == "" {
+		cfg.Dir = DefaultDir()
+	}
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = DefaultMaxSize
+	}
+	if cfg.MaxAge == 0 {
+		cfg.MaxAge = DefaultMaxAge
+	}
+	if cfg.MaxOutput == 0 {
+		cfg.MaxOutput = DefaultMaxOutput
+	}
+	if cfg.Logs.MaxAge == 0 {
+		cfg.Logs.MaxAge = DefaultLogMaxAge
+	}
+	if cfg.Logs.MaxFiles == 0 {
+		cfg.Logs.MaxFiles = DefaultLogMaxFiles
+	}
+	if cfg.Logs.MaxSize == 0 {
+		cfg.Logs.MaxSize = DefaultLogMaxSize
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if cfg.Dir != store.cfg.Dir {
+		store.loaded = false
+	}
+	store.cfg = cfg
+}
+
+// MaxOutput is the number of bytes of stdout and stderr kept for each entry
+func (store *Store) MaxOutput() int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return int(store.cfg.MaxOutput)
+}
+
+// Append adds entry to the end of the history, unless the history is disabled.
+// The event of the entry is redacted, see Redact. Once the history exceeds its
+// retention it is compacted in the background, so that Append does not hold
+// up the job that called it
+func (store *Store) Append(entry Entry) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.cfg.Disabled {
+		return nil
+	}
+
+	if !store.loaded {
+		err := store.load()
+		if err != nil {
+			return err
+		}
+	}
+
+	entry.Event = Redact(entry.Event)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	file, err := os.OpenFile(store.path(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(line)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	store.size += int64(len(line))
+	if store.oldest.IsZero() {
+		store.oldest = entry.Finished
+	}
+
+	now := store.now()
+	expired := now.Sub(store.oldest) > store.cfg.MaxAge && now.Sub(store.compacted) > compactInterval
+
+	if store.size > int64(store.cfg.MaxSize) || expired {
+		store.compactLater()
+	}
+
+	return nil
+}
+
+// Wait waits for a compaction in the background to complete
+func (store *Store) Wait() {
+	store.compactions.Wait()
+}
+
+// compactLater compacts the history in the background, unless it already
+// is being compacted. Must be called with mu held
+func (store *Store) compactLater() {
+	if store.compacting {
+		return
+	}
+	store.compacting = true
+
+	store.compactions.Add(1)
+	go func() {
+		defer store.compactions.Done()
+
+		err := store.compact()
+		if err != nil {
+			store.logger.WithError(err).Warn("Failed to compact history")
+		}
+	}()
+}
+
+// path is the path of the history file. Must be called with mu held
+func (store *Store) path() string {
+	return filepath.Join(store.cfg.Dir, FileName)
+}
+
+// load creates the history directory and reads the size and oldest entry
+// of the history file, if it exists. Must be called with mu held
+func (store *Store) load() error {
+	err := os.MkdirAll(store.cfg.Dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	store.size = 0
+	store.oldest = time.Time{}
+
+	info, err := os.Stat(store.path())
+	if err == nil {
+		store.size = info.Size()
+		err = scan(store.path(), func(entry Entry, _ []byte) {
+			if store.oldest.IsZero() {
+				store.oldest = entry.Finished
+			}
+		})
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	store.loaded = true
+	return nil
+}
+
+// compact rewrites the history without entries older than max_age, then
+// removes the oldest entries until it is at most half of max_size, so that
+// the history is not rewritten on every append. The newest entry is always
+// kept. The history is read without holding mu, entries appended meanwhile
+// are copied as they are once mu is held to replace the file
+func (store *Store) compact() error {
+	store.mu.Lock()
+	cfg := store.cfg
+	path := store.path()
+	scanned := store.size
+	store.mu.Unlock()
+
+	cutoff := store.now().Add(-cfg.MaxAge)
+
+	var lines [][]byte
+	var finished []time.Time
+	total := 0
+
+	err := scanLimited(path, scanned, func(entry Entry, line []byte) {
+		total++
+		if entry.Finished.Before(cutoff) {
+			return
+		}
+		lines = append(lines, append([]byte(nil), line...))
+		finished = append(finished, entry.Finished)
+	})
+	if err != nil {
+		store.compactDone()
+		return err
+	}
+
+	//Keep the newest entries that fit, and at least the newest
+	var size int64
+	first := len(lines)
+	for first > 0 {
+		lineSize := int64(len(lines[first-1]) + 1)
+		if first < len(lines) && size+lineSize > int64(cfg.MaxSize)/2 {
+			break
+		}
+		first--
+		size += lineSize
+	}
+
+	temp, err := os.CreateTemp(cfg.Dir, FileName+".*")
+	if err != nil {
+		store.compactDone()
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	writer := bufio.NewWriter(temp)
+	for _, line := range lines[first:] {
+		writer.Write(line)
+		writer.WriteByte('\n')
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.compacting = false
+
+	if store.cfg.Dir != cfg.Dir || !store.loaded {
+		//The history has moved, so this one is left as it is
+		temp.Close()
+		return nil
+	}
+
+	appended, err := copyFrom(writer, path, scanned)
+	if err == nil {
+		err = writer.Flush()
+	}
+	closeErr := temp.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	err = os.Rename(temp.Name(), path)
+	if err != nil {
+		return err
+	}
+
+	store.logger.
+		WithField("removed", total-len(lines)+first).
+		WithField("kept", len(lines)-first).
+		Debug("History compacted")
+
+	store.size = size + appended
+	store.oldest = time.Time{}
+	if first < len(finished) {
+		store.oldest = finished[first]
+	}
+	store.compacted = store.now()
+
+	//Entries appended while compacting can leave the history too large
+	if appended > 0 && store.size > int64(store.cfg.MaxSize) {
+		store.compactLater()
+	}
+
+	return nil
+}
+
+// compactDone clears compacting after a compaction failed
+func (store *Store) compactDone() {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.compacting = false
+}
+
+// copyFrom copies the file at path from offset to writer, returning the
+// number of bytes copied
+func copyFrom(writer io.Writer, path string, offset int64) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	return io.Copy(writer, file)
+}
@@ -0,0 +1,157 @@
+package logging
+
+import (
+	"io"
+	"os"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultMaxBackups is the number of rotated files kept, if max_size
+// is set but max_backups is not
+var DefaultMaxBackups = 3
+
+// ServiceField is the field of entries that are logged for a service,
+// the level of these entries can be overridden per service
+const ServiceField = "svc"
+
+// Output is a configured format and destination of logs, see Open
+type Output struct {
+	cfg       config.Logging
+	formatter logrus.Formatter
+	writer    io.Writer
+	hooks     logrus.LevelHooks
+	level     logrus.Level
+	closer    io.Closer
+}
+
+// Open opens the output of cfg, zero values are replaced by the
+// defaults: info level, text format and stderr
+func Open(cfg config.Logging) (*Output, error) {
+	level, err := parseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &filterFormatter{
+		level:    level,
+		services: make(map[string]logrus.Level, len(cfg.Services)),
+	}
+
+	//The logger must let through entries of the most verbose level,
+	//the formatter drops those that are not wanted
+	maxLevel := level
+	for service, serviceLevel := range cfg.Services {
+		parsed, err := parseLevel(serviceLevel)
+		if err != nil {
+			return nil, err
+		}
+		filter.services[service] = parsed
+		if parsed > maxLevel {
+			maxLevel = parsed
+		}
+	}
+
+	switch cfg.Format {
+	case config.JsonFormat:
+		filter.formatter = &logrus.JSONFormatter{}
+	case config.LogfmtFormat:
+		filter.formatter = &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}
+	default:
+		filter.formatter = &logrus.TextFormatter{FullTimestamp: true}
+	}
+
+	output := &Output{
+		cfg:       cfg,
+		formatter: filter,
+		writer:    os.Stderr,
+		hooks:     make(logrus.LevelHooks),
+		level:     maxLevel,
+	}
+
+	switch cfg.Output {
+	case "", config.StderrOutput:
+	case config.SyslogOutput:
+		hook, err := newSyslogHook(filter)
+		if err != nil {
+			return nil, err
+		}
+		output.hooks.Add(hook)
+		output.writer = io.Discard
+		output.closer = hook
+	default:
+		maxBackups := cfg.MaxBackups
+		if cfg.MaxSize > 0 && maxBackups == 0 {
+			maxBackups = DefaultMaxBackups
+		}
+
+		file, err := openRotating(cfg.Output, int64(cfg.MaxSize), maxBackups)
+		if err != nil {
+			return nil, err
+		}
+		output.writer = file
+		output.closer = file
+	}
+
+	return output, nil
+}
+
+// Config is the config the output was opened with
+func (output *Output) Config() config.Logging {
+	return output.cfg
+}
+
+// Apply directs the entries of logger to the output
+func (output *Output) Apply(logger *logrus.Logger) {
+	logger.SetFormatter(output.formatter)
+	logger.SetOutput(output.writer)
+	logger.ReplaceHooks(output.hooks)
+	logger.SetLevel(output.level)
+}
+
+// Close closes the file or syslog connection of the output, if any.
+// The output must no longer be applied to a logger
+func (output *Output) Close() error {
+	if output.closer == nil {
+		return nil
+	}
+	return output.closer.Close()
+}
+
+// parseLevel parses a configured level, an empty level is info
+func parseLevel(level string) (logrus.Level, error) {
+	if level == "" {
+		return logrus.InfoLevel, nil
+	}
+	return logrus.ParseLevel(level)
+}
+
+// filterFormatter drops the entries that are more verbose than the level
+// of their service, or the level of the output if the service has none
+type filterFormatter struct {
+	formatter logrus.Formatter
+	level     logrus.Level
+	services  map[string]logrus.Level
+}
+
+// Format formats entry, or returns nothing if it is dropped
+func (filter *filterFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if !filter.enabled(entry) {
+		return nil, nil
+	}
+	return filter.formatter.Format(entry)
+}
+
+// enabled reports whether entry should be written
+func (filter *filterFormatter) enabled(entry *logrus.Entry) bool {
+	level := filter.level
+
+	if service, ok := entry.Data[ServiceField].(string); ok {
+		if serviceLevel, exists := filter.services[service]; exists {
+			level = serviceLevel
+		}
+	}
+
+	return entry.Level <= level
+}
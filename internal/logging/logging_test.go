@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// logger constructs a logger with output applied, writing to a buffer
+func logger(t *testing.T, cfg config.Logging) (*logrus.Logger, *bytes.Buffer) {
+	t.Helper()
+
+	output, err := Open(cfg)
+	assert.NoError(t, err)
+
+	logger := logrus.New()
+	output.Apply(logger)
+
+	buffer := &bytes.Buffer{}
+	logger.SetOutput(buffer)
+	return logger, buffer
+}
+
+func TestOpenDefaults(t *testing.T) {
+	logger, buffer := logger(t, config.Logging{})
+
+	logger.Debug("hidden")
+	logger.Info("shown")
+
+	assert.Equal(t, logrus.InfoLevel, logger.GetLevel())
+	assert.NotContains(t, buffer.String(), "hidden")
+	assert.Contains(t, buffer.String(), "msg=shown")
+}
+
+func TestOpenJson(t *testing.T) {
+	logger, buffer := logger(t, config.Logging{Format: config.JsonFormat})
+
+	logger.WithField(ServiceField, "a").Warn("shown")
+
+	assert.Contains(t, buffer.String(), `"svc":"a"`)
+	assert.Contains(t, buffer.String(), `"msg":"shown"`)
+}
+
+func TestOpenInvalidLevel(t *testing.T) {
+	_, err := Open(config.Logging{Level: "loud"})
+	assert.Error(t, err)
+}
+
+func TestServiceLevels(t *testing.T) {
+	logger, buffer := logger(t, config.Logging{
+		Level:    "warn",
+		Services: map[string]string{"noisy": "error", "debugged": "debug"},
+	})
+
+	logger.WithField(ServiceField, "debugged").Debug("debugged-debug")
+	logger.WithField(ServiceField, "noisy").Warn("noisy-warn")
+	logger.WithField(ServiceField, "other").Info("other-info")
+	logger.WithField(ServiceField, "other").Warn("other-warn")
+	logger.Debug("global-debug")
+
+	assert.Equal(t, logrus.DebugLevel, logger.GetLevel())
+	assert.Contains(t, buffer.String(), "debugged-debug")
+	assert.NotContains(t, buffer.String(), "noisy-warn")
+	assert.NotContains(t, buffer.String(), "other-info")
+	assert.Contains(t, buffer.String(), "other-warn")
+	assert.NotContains(t, buffer.String(), "global-debug")
+}
+
+func TestFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "saucisson.log")
+
+	output, err := Open(config.Logging{Output: path, MaxSize: 100, MaxBackups: 2})
+	assert.NoError(t, err)
+
+	logger := logrus.New()
+	output.Apply(logger)
+
+	for i := 0; i < 20; i++ {
+		logger.Info("rotated")
+	}
+	assert.NoError(t, output.Close())
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(100))
+
+	for _, backup := range []string{".1", ".2"} {
+		info, err := os.Stat(path + backup)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(100))
+	}
+
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileWithoutRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "saucisson.log")
+
+	output, err := Open(config.Logging{Output: path, Format: config.LogfmtFormat})
+	assert.NoError(t, err)
+
+	logger := logrus.New()
+	output.Apply(logger)
+	logger.Info("written")
+	assert.NoError(t, output.Close())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "msg=written")
+
+	_, err = os.Stat(path + ".1")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,23 @@
+//go:build !unix
+
+package logging
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// syslogHook is never constructed where syslog is not available
+type syslogHook struct {
+	logrus.Hook
+}
+
+// newSyslogHook is unsupported where syslog is not available
+func newSyslogHook(formatter logrus.Formatter) (*syslogHook, error) {
+	return nil, errors.New("syslog output is not supported on this platform")
+}
+
+func (hook *syslogHook) Close() error {
+	return nil
+}
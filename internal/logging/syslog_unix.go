@@ -0,0 +1,55 @@
+//go:build unix
+
+package logging
+
+import (
+	"log/syslog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// syslogHook writes entries to the local syslog socket,
+// with the priority corresponding to the level of the entry
+type syslogHook struct {
+	formatter logrus.Formatter
+	writer    *syslog.Writer
+}
+
+func newSyslogHook(formatter logrus.Formatter) (*syslogHook, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, "saucisson")
+	if err != nil {
+		return nil, err
+	}
+
+	return &syslogHook{formatter: formatter, writer: writer}, nil
+}
+
+func (hook *syslogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (hook *syslogHook) Fire(entry *logrus.Entry) error {
+	line, err := hook.formatter.Format(entry)
+	if err != nil || len(line) == 0 {
+		return err
+	}
+
+	message := string(line)
+
+	switch entry.Level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return hook.writer.Crit(message)
+	case logrus.ErrorLevel:
+		return hook.writer.Err(message)
+	case logrus.WarnLevel:
+		return hook.writer.Warning(message)
+	case logrus.InfoLevel:
+		return hook.writer.Info(message)
+	default:
+		return hook.writer.Debug(message)
+	}
+}
+
+func (hook *syslogHook) Close() error {
+	return hook.writer.Close()
+}
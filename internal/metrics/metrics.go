@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Default is the registry that the metrics of saucisson are registered with,
+// along with those of the Go runtime and the process
+var Default = prometheus.NewRegistry()
+
+// Factory constructs metrics that are registered with Default
+var Factory = promauto.With(Default)
+
+// DefaultBuckets are the upper bounds of the buckets of a histogram of
+// durations in seconds, from 5ms to 10s
+var DefaultBuckets = prometheus.DefBuckets
+
+func init() {
+	Default.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+// Path is the path that metrics are served on
+const Path = "/metrics"
+
+// readHeaderTimeout bounds how long a client can take to send the headers of
+// a request, so that slow clients cannot hold connections open
+const readHeaderTimeout = 10 * time.Second
+
+// Server serves the metrics of a registry over HTTP
+type Server struct {
+	logger   logrus.FieldLogger
+	registry prometheus.Gatherer
+
+	runningMu sync.Mutex
+	server    *http.Server
+}
+
+// NewServer constructs a server for registry, it does not listen until Listen is called
+func NewServer(logger logrus.FieldLogger, registry prometheus.Gatherer) *Server {
+	return &Server{
+		logger:   logger,
+		registry: registry,
+	}
+}
+
+// Listen binds to the provided address and serves metrics on a separate
+// goroutine. If the server is already listening then it is stopped once the
+// new address is bound. If binding fails the server is unchanged
+func (s *Server) Listen(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	err = s.Stop(context.Background())
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(Path, s.Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: readHeaderTimeout}
+
+	s.runningMu.Lock()
+	s.server = server
+	s.runningMu.Unlock()
+
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.
+				WithError(err).
+				WithField("address", address).
+				Error("Metrics server failed")
+		}
+	}()
+
+	s.logger.
+		WithField("address", listener.Addr().String()).
+		Debug("Metrics server listening")
+
+	return nil
+}
+
+// Handler serves the metrics of the registry in the Prometheus text format
+func (s *Server) Handler() http.Handler {
+	return promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{ErrorLog: s.logger})
+}
+
+// Stop gracefully shuts down the server, waiting for in-flight requests
+// until the context is done. If the server is not listening this noops
+func (s *Server) Stop(ctx context.Context) error {
+	s.runningMu.Lock()
+	server := s.server
+	s.server = nil
+	s.runningMu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	return server.Shutdown(ctx)
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_total", Help: "Counts"}, []string{"service"})
+	registry.MustRegister(counter)
+	counter.WithLabelValues(`quoted "value"`).Inc()
+
+	recorder := httptest.NewRecorder()
+	NewServer(logrus.New(), registry).Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+
+	body, _ := io.ReadAll(recorder.Body)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "version=0.0.4")
+	assert.Contains(t, string(body), `test_total{service="quoted \"value\""} 1`)
+}
+
+func TestDefault(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewServer(logrus.New(), Default).Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+
+	body, _ := io.ReadAll(recorder.Body)
+	assert.Contains(t, string(body), "go_goroutines")
+}
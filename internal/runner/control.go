@@ -0,0 +1,287 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/control"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/mickyco94/saucisson/internal/executor"
+	"github.com/mickyco94/saucisson/internal/history"
+)
+
+// TriggerCondition is the condition of events created by the trigger command
+const TriggerCondition config.Condition = "trigger"
+
+// ServeControl answers the requests received on the control socket
+func (runner *Runner) ServeControl(req control.Request) control.Response {
+	var err error
+	res := control.Response{}
+
+	switch req.Command {
+	case control.StatusCommand:
+		res.Status = runner.status()
+	case control.ListCommand:
+		res.Services = runner.list()
+	case control.TriggerCommand:
+		err = runner.trigger(req.Service)
+	case control.PauseCommand:
+		err = runner.setPaused(req.Service, true)
+	case control.ResumeCommand:
+		err = runner.setPaused(req.Service, false)
+	default:
+		err = fmt.Errorf("unknown command %q", req.Command)
+	}
+
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
+// status reports the uptime of the runner and the health of its dependencies
+func (runner *Runner) status() *control.Status {
+	watcher := func(name string) control.WatcherStatus {
+		status := control.WatcherStatus{Name: name, State: control.Running}
+		if err := runner.failure(name); err != nil {
+			status.State = control.Failed
+			status.Error = err.Error()
+		}
+		return status
+	}
+
+	runner.servicesMu.Lock()
+	webhook := watcher("webhook")
+	if runner.webhookAddress == "" {
+		webhook.State = control.Disabled
+	}
+	webhook.Detail = runner.webhookAddress
+
+	pools := make([]control.PoolStatus, 0, len(runner.pools))
+	for name, pool := range runner.pools {
+		pools = append(pools, poolStatus(name, pool))
+	}
+	runner.servicesMu.Unlock()
+
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].Name < pools[j].Name
+	})
+
+	return &control.Status{
+		Started: runner.started,
+		Uptime:  time.Since(runner.started),
+		Watchers: []control.WatcherStatus{
+			watcher("cron"),
+			watcher("file"),
+			watcher("process"),
+			webhook,
+		},
+		Pool:  poolStatus(config.DefaultPool, runner.pool),
+		Pools: pools,
+	}
+}
+
+func poolStatus(name string, pool *executor.Pool) control.PoolStatus {
+	stats := pool.Stats()
+
+	return control.PoolStatus{
+		Name:    name,
+		Size:    stats.Size,
+		Busy:    stats.Busy,
+		Queued:  stats.Queued,
+		Dropped: stats.Dropped,
+	}
+}
+
+// list reports every service, ordered by name
+func (runner *Runner) list() []control.ServiceStatus {
+	runner.servicesMu.Lock()
+	defer runner.servicesMu.Unlock()
+
+	runner.stateMu.Lock()
+	defer runner.stateMu.Unlock()
+
+	services := make([]control.ServiceStatus, 0, len(runner.services))
+	for name, svc := range runner.services {
+		conditions := make([]string, len(svc.def.conditions))
+		for i, cond := range svc.def.conditions {
+			conditions[i] = cond.String()
+		}
+
+		stats := svc.gate.Stats()
+
+		services = append(services, control.ServiceStatus{
+			Name:       name,
+			Conditions: conditions,
+			Executor:   svc.def.executorType,
+			Paused:     runner.paused[name],
+			Skipped:    stats.Skipped,
+			Cancelled:  stats.Cancelled,
+			LastResult: runner.results[name],
+		})
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	return services
+}
+
+// trigger queues a job for the service, regardless of whether it is paused.
+// The concurrency policy of the service still applies
+func (runner *Runner) trigger(name string) error {
+	runner.servicesMu.Lock()
+	svc, exists := runner.services[name]
+	runner.servicesMu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("service %q does not exist", name)
+	}
+
+	//A trigger skipped by the concurrency policy has already been logged
+	err := svc.submit(event.New(TriggerCondition))
+	var notRun *executor.NotRunError
+	if err != nil && !errors.As(err, &notRun) {
+		return err
+	}
+
+	runner.logger.WithField("svc", name).Info("Service triggered")
+	return nil
+}
+
+// setPaused pauses or resumes the service. Events satisfying the conditions
+// of a paused service are ignored
+func (runner *Runner) setPaused(name string, paused bool) error {
+	runner.servicesMu.Lock()
+	_, exists := runner.services[name]
+	runner.servicesMu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("service %q does not exist", name)
+	}
+
+	runner.stateMu.Lock()
+	if paused {
+		runner.paused[name] = true
+	} else {
+		delete(runner.paused, name)
+	}
+	runner.stateMu.Unlock()
+
+	message := "Service resumed"
+	if paused {
+		message = "Service paused"
+	}
+	runner.logger.WithField("svc", name).Info(message)
+
+	return nil
+}
+
+func (runner *Runner) isPaused(name string) bool {
+	runner.stateMu.Lock()
+	defer runner.stateMu.Unlock()
+
+	return runner.paused[name]
+}
+
+// record stores the result of the latest execution of a service,
+// and appends the execution to the history
+func (runner *Runner) record(id string, ev event.Event, started time.Time, report *executor.Report, err error) {
+	finished := time.Now()
+
+	result := &control.Result{
+		Started:  started,
+		Duration: finished.Sub(started),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	runner.stateMu.Lock()
+	runner.results[ev.Service] = result
+	runner.stateMu.Unlock()
+
+	entry := history.Entry{
+		ID:          id,
+		Service:     ev.Service,
+		Event:       ev,
+		Started:     started,
+		Finished:    finished,
+		Duration:    result.Duration,
+		Result:      history.ResultOf(err),
+		Error:       result.Error,
+		HttpStatus:  report.HttpStatus(),
+		Termination: report.Termination(),
+	}
+	entry.Stdout, entry.Stderr, entry.Truncated = report.Output()
+
+	var limitErr *executor.LimitError
+	if errors.As(err, &limitErr) && !limitErr.Possible {
+		entry.Limit = limitErr.Limit
+	}
+
+	jobResults.WithLabelValues(ev.Service, string(entry.Result)).Inc()
+	jobDuration.WithLabelValues(ev.Service).Observe(entry.Duration.Seconds())
+
+	err = runner.history.Append(entry)
+	if err != nil {
+		runner.logger.
+			WithError(err).
+			WithField("svc", ev.Service).
+			WithField("job", id).
+			Warn("Failed to record execution in history")
+	}
+}
+
+// openLog creates the log of a job, a log that cannot be created is
+// logged and the job runs without one
+func (runner *Runner) openLog(service string, id string) *history.JobLog {
+	log, err := runner.history.OpenLog(service, id)
+	if err != nil {
+		runner.logger.
+			WithError(err).
+			WithField("svc", service).
+			WithField("job", id).
+			Warn("Failed to create job log")
+	}
+	return log
+}
+
+// closeLog closes the log of a job, logging any failure to write it
+func (runner *Runner) closeLog(service string, id string, log *history.JobLog) {
+	err := log.Close()
+	if err != nil {
+		runner.logger.
+			WithError(err).
+			WithField("svc", service).
+			WithField("job", id).
+			Warn("Failed to write job log")
+	}
+}
+
+// forget discards the state of a service that has been removed from the config
+func (runner *Runner) forget(name string) {
+	runner.stateMu.Lock()
+	delete(runner.paused, name)
+	delete(runner.results, name)
+	runner.stateMu.Unlock()
+}
+
+// fail marks a watcher as having failed
+func (runner *Runner) fail(watcher string, err error) {
+	runner.stateMu.Lock()
+	runner.failures[watcher] = err
+	runner.stateMu.Unlock()
+}
+
+func (runner *Runner) failure(watcher string) error {
+	runner.stateMu.Lock()
+	defer runner.stateMu.Unlock()
+
+	return runner.failures[watcher]
+}
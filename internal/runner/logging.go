@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// openLogging opens the output of cfg, with the overrides of the command
+// line applied. If the output is unchanged nil is returned. The output is
+// not used until it is passed to useLogging
+func (runner *Runner) openLogging(cfg config.Logging) (*logging.Output, error) {
+	if runner.logOverrides.Level != "" {
+		cfg.Level = runner.logOverrides.Level
+	}
+	if runner.logOverrides.Format != "" {
+		cfg.Format = runner.logOverrides.Format
+	}
+	if runner.logOverrides.Output != "" && runner.logOverrides.Output != cfg.Output {
+		//Rotation only applies to the configured file
+		cfg.Output = runner.logOverrides.Output
+		cfg.MaxSize = 0
+		cfg.MaxBackups = 0
+	}
+
+	if runner.logOutput != nil && reflect.DeepEqual(cfg, runner.logging) {
+		return nil, nil
+	}
+
+	if errs := cfg.Validate(); len(errs) > 0 {
+		return nil, fmt.Errorf("logging: %w", errs[0])
+	}
+
+	output, err := logging.Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("logging: %w", err)
+	}
+
+	return output, nil
+}
+
+// useLogging directs logs to output, closing the previous output.
+// The config of output is kept, so that it is not opened again
+func (runner *Runner) useLogging(output *logging.Output) {
+	output.Apply(runner.baseLogger)
+	runner.logging = output.Config()
+
+	if runner.logOutput != nil {
+		err := runner.logOutput.Close()
+		if err != nil {
+			runner.logger.WithError(err).Warn("Failed to close previous log output")
+		}
+	}
+
+	runner.logOutput = output
+}
+
+// closeLogging directs logs back to stderr and closes the output
+func (runner *Runner) closeLogging() {
+	if runner.logOutput == nil {
+		return
+	}
+
+	runner.baseLogger.SetOutput(os.Stderr)
+	runner.baseLogger.ReplaceHooks(make(logrus.LevelHooks))
+	runner.logOutput.Close()
+	runner.logOutput = nil
+}
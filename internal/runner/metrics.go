@@ -0,0 +1,26 @@
+package runner
+
+import (
+	"github.com/mickyco94/saucisson/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	conditionTriggers = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "saucisson_condition_triggers_total",
+		Help: "Number of times a condition of a service was satisfied, by condition type",
+	}, []string{"service", "condition"})
+	jobResults = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "saucisson_jobs_total",
+		Help: "Number of executions of a service that have completed, by result",
+	}, []string{"service", "result"})
+	jobDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "saucisson_job_duration_seconds",
+		Help:    "Duration of the executions of a service",
+		Buckets: []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300, 1800},
+	}, []string{"service"})
+	configReloads = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "saucisson_config_reloads_total",
+		Help: "Number of config reloads, by result",
+	}, []string{"result"})
+)
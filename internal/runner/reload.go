@@ -0,0 +1,479 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/mickyco94/saucisson/internal/executor"
+	"github.com/mickyco94/saucisson/internal/watcher"
+	"github.com/sirupsen/logrus"
+)
+
+// service is a definition that has been registered with the watchers
+type service struct {
+	def *definition
+
+	//submit runs the executor of the service for the event, subject to
+	//the concurrency policy, regardless of whether the service is paused
+	submit func(event.Event) error
+	gate   *executor.Gate
+
+	//deregister removes every handler registered for the service
+	deregister func()
+
+	//active is set once the config of the service is committed, until then
+	//its handlers ignore events, so that a service that replaces another
+	//never runs alongside it
+	active int32
+}
+
+// reload reads the config again and applies it. A config that cannot be
+// loaded is rejected and the previous config continues to run.
+func (runner *Runner) reload() {
+	cfg, err := load(runner.templatePath)
+	if err == nil {
+		err = runner.apply(cfg)
+	}
+
+	if err != nil {
+		configReloads.WithLabelValues("failure").Inc()
+		runner.logger.
+			WithError(err).
+			WithField("path", runner.templatePath).
+			Error("Config reload rejected, continuing with previous config")
+		return
+	}
+
+	configReloads.WithLabelValues("success").Inc()
+	runner.logger.
+		WithField("path", runner.templatePath).
+		Info("Config reloaded")
+}
+
+// apply diffs cfg against the currently registered services.
+// Services that are new or have changed are registered, services that have
+// changed or been removed are deregistered, and unchanged services are left
+// as is. The executor pool, and any running jobs, are unaffected.
+//
+// If any service fails to construct or register, or a server fails to
+// listen, then every change is undone and the previous config continues
+// to run unchanged.
+func (runner *Runner) apply(cfg *config.Raw) (err error) {
+	definitions, err := runner.constructAll(cfg)
+	if err != nil {
+		return err
+	}
+
+	runner.servicesMu.Lock()
+	defer runner.servicesMu.Unlock()
+
+	//rollback undoes the changes made so far, in reverse, if apply fails
+	var rollback []func()
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(rollback) - 1; i >= 0; i-- {
+			rollback[i]()
+		}
+	}()
+
+	logOutput, err := runner.openLogging(cfg.Logging)
+	if err != nil {
+		return err
+	}
+	if logOutput != nil {
+		rollback = append(rollback, func() { logOutput.Close() })
+	}
+
+	addedPools := runner.addPools(cfg)
+	rollback = append(rollback, func() { runner.stopPools(addedPools) })
+
+	next := make(map[string]*service, len(definitions))
+	added := make([]*service, 0)
+	rollback = append(rollback, func() {
+		for _, svc := range added {
+			svc.deregister()
+		}
+	})
+
+	for _, def := range definitions {
+		existing, exists := runner.services[def.name]
+		if exists && existing.def.fingerprint == def.fingerprint {
+			next[def.name] = existing
+			continue
+		}
+
+		svc, err := runner.register(def)
+		if err != nil {
+			return fmt.Errorf("%s: %w", def.name, err)
+		}
+
+		added = append(added, svc)
+		next[def.name] = svc
+	}
+
+	//The servers are moved once every service has registered, as binding
+	//can still fail, and are moved back if it does
+	webhookAddress := runner.webhookAddress
+	err = runner.listenWebhook(cfg.Webhook.Address)
+	if err != nil {
+		return err
+	}
+	rollback = append(rollback, func() { runner.restoreListener("webhook", runner.listenWebhook, webhookAddress) })
+
+	err = runner.listenMetrics(cfg.Metrics.Address)
+	if err != nil {
+		return err
+	}
+
+	//Nothing can fail from here on, so the config is committed
+	runner.configurePools(cfg)
+
+	//Replaced services are deregistered before their replacements handle events,
+	//and the idle connections of their executors are closed
+	removed := 0
+	for name, svc := range runner.services {
+		if next[name] != svc {
+			svc.deregister()
+			executor.CloseIdleConnections(svc.def.executor)
+			removed++
+		}
+		if _, exists := next[name]; !exists {
+			runner.forget(name)
+		}
+	}
+
+	for _, svc := range added {
+		atomic.StoreInt32(&svc.active, 1)
+	}
+
+	runner.services = next
+	runner.history.Configure(cfg.History)
+
+	if logOutput != nil {
+		runner.useLogging(logOutput)
+	}
+
+	//Removed pools are no longer used by any service
+	runner.stopPools(runner.removedPools(cfg))
+
+	runner.logger.
+		WithField("registered", len(added)).
+		WithField("deregistered", removed).
+		WithField("unchanged", len(next)-len(added)).
+		Debug("Services applied")
+
+	return nil
+}
+
+// register adds each condition of the definition to the watchers
+func (runner *Runner) register(def *definition) (*service, error) {
+	serviceName := def.name
+	execute := func(ctx context.Context, id string, ev event.Event) error {
+		started := time.Now()
+		report := executor.NewReport(runner.history.MaxOutput())
+
+		log := runner.openLog(serviceName, id)
+		if log != nil {
+			report.SetLog(log)
+		}
+
+		ctx = executor.WithReport(executor.WithJobID(ctx, id), report)
+		err := executor.Recovered(def.executor).Execute(ctx, ev)
+
+		if log != nil {
+			runner.closeLog(serviceName, id, log)
+		}
+		runner.record(id, ev, started, report, err)
+
+		//Exit errors of exec already hold the stderr of the process
+		var exitErr *executor.ExitError
+		if tail := report.StderrTail(); err != nil && tail != "" && !(errors.As(err, &exitErr) && exitErr.Stderr != "") {
+			err = &executor.StderrError{Err: err, Tail: tail}
+		}
+		return err
+	}
+
+	pool := runner.pool
+	if def.pool != "" {
+		pool = runner.pools[def.pool]
+	}
+
+	gate := executor.NewGate(runner.logger, pool, def.concurrency)
+
+	submit := func(ev event.Event) error {
+		ev.Service = serviceName
+		id := executor.NewJobID()
+		job := executor.Job{
+			ID:      id,
+			Service: serviceName,
+			Event:   ev,
+			Executor: func(ctx context.Context, ev event.Event) error {
+				return execute(ctx, id, ev)
+			},
+		}
+
+		// Webhook and control requests are answered synchronously, so must not wait for a worker
+		wait := ev.Condition != config.WebhookKey && ev.Condition != TriggerCondition
+
+		return gate.Submit(job, wait)
+	}
+
+	limited, stop := limit(runner.logger.WithField("svc", serviceName), def, submit)
+
+	svc := &service{def: def, submit: submit, gate: gate}
+
+	queueJob := func(ev event.Event) error {
+		if atomic.LoadInt32(&svc.active) == 0 {
+			return &executor.NotRunError{Reason: "service is not active yet"}
+		}
+
+		conditionTriggers.WithLabelValues(serviceName, string(ev.Condition)).Inc()
+
+		if runner.isPaused(serviceName) {
+			runner.logger.
+				WithField("svc", serviceName).
+				WithField("condition", ev.Condition).
+				Debug("Service is paused, ignoring event")
+			return &executor.NotRunError{Reason: "service is paused"}
+		}
+
+		return limited(ev)
+	}
+
+	deregisters := []func(){stop}
+	deregisterAll := func() {
+		for _, deregister := range deregisters {
+			deregister()
+		}
+	}
+
+	for _, cond := range def.conditions {
+		deregister, err := runner.registerCondition(cond, queueJob)
+		if err != nil {
+			deregisterAll()
+			return nil, err
+		}
+		deregisters = append(deregisters, deregister)
+	}
+
+	svc.deregister = deregisterAll
+	return svc, nil
+}
+
+// limit applies the threshold, debounce and throttle of the definition, in
+// that order, to the events passed on to handler. The returned function
+// discards any events that are waiting to be passed on
+func limit(logger logrus.FieldLogger, def *definition, handler func(event.Event) error) (func(event.Event) error, func()) {
+	stops := make([]func(), 0, 2)
+	stop := func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+
+	if def.throttle > 0 {
+		throttle := watcher.NewThrottle(logger, def.throttle, handler)
+		handler = throttle.Handle
+		stops = append(stops, throttle.Stop)
+	}
+
+	if def.debounce > 0 {
+		debounce := watcher.NewDebounce(logger, def.debounce, def.maxWait, handler)
+		handler = debounce.Handle
+		stops = append(stops, debounce.Stop)
+	}
+
+	if def.threshold != nil {
+		handler = watcher.NewThreshold(def.threshold.Count, def.threshold.Window, handler).Handle
+	}
+
+	return handler, stop
+}
+
+// registerCondition registers the leaves of the condition tree with their
+// watchers, composing the handlers of branches so that handler is invoked
+// when the condition as a whole is satisfied.
+// Only the webhook watcher makes use of errors returned by handler.
+// The returned function deregisters every leaf.
+func (runner *Runner) registerCondition(cond *condition, handler func(event.Event) error) (func(), error) {
+	ignoreErr := func(ev event.Event) { handler(ev) }
+
+	switch {
+	case cond.file != nil:
+		id, err := runner.file.HandleFunc(cond.file, ignoreErr)
+		if err != nil {
+			return nil, err
+		}
+		return func() { runner.file.Remove(id) }, nil
+	case cond.cron != nil:
+		id, err := runner.cron.HandleFunc(cond.cron, ignoreErr)
+		if err != nil {
+			return nil, err
+		}
+		return func() { runner.cron.Remove(id) }, nil
+	case cond.process != nil:
+		id := runner.process.HandleFunc(cond.process, ignoreErr)
+		return func() { runner.process.Remove(id) }, nil
+	case cond.webhook != nil:
+		id := runner.webhook.HandleFunc(cond.webhook, handler)
+		return func() { runner.webhook.Remove(id) }, nil
+	}
+
+	var child func(i int) func(event.Event) error
+
+	switch cond.kind {
+	case config.AllKey:
+		child = watcher.NewAll(len(cond.children), cond.window, handler).Child
+	case config.SequenceKey:
+		child = watcher.NewSequence(len(cond.children), cond.window, handler).Child
+	default:
+		child = func(int) func(event.Event) error { return handler }
+	}
+
+	deregisters := make([]func(), 0, len(cond.children))
+	deregisterAll := func() {
+		for _, deregister := range deregisters {
+			deregister()
+		}
+	}
+
+	for i, c := range cond.children {
+		deregister, err := runner.registerCondition(c, child(i))
+		if err != nil {
+			deregisterAll()
+			return nil, err
+		}
+		deregisters = append(deregisters, deregister)
+	}
+
+	return deregisterAll, nil
+}
+
+// addPools starts the named pools of cfg that are new, returning them so
+// that they can be stopped if the config is not applied
+func (runner *Runner) addPools(cfg *config.Raw) map[string]*executor.Pool {
+	added := make(map[string]*executor.Pool)
+
+	for name, spec := range cfg.Pools {
+		if _, exists := runner.pools[name]; exists {
+			continue
+		}
+
+		pool := executor.NewPool(runner.logger.WithField("pool", name), name, spec.Size)
+		pool.Configure(spec)
+		pool.Start()
+
+		added[name] = pool
+		runner.pools[name] = pool
+	}
+
+	return added
+}
+
+// configurePools reconfigures the shared pool and the named pools of cfg
+func (runner *Runner) configurePools(cfg *config.Raw) {
+	runner.pool.Configure(cfg.Pool)
+
+	for name, spec := range cfg.Pools {
+		pool := runner.pools[name]
+		pool.Configure(spec)
+		pool.Start()
+	}
+}
+
+// removedPools returns the named pools that are not in cfg
+func (runner *Runner) removedPools(cfg *config.Raw) map[string]*executor.Pool {
+	removed := make(map[string]*executor.Pool)
+
+	for name, pool := range runner.pools {
+		if _, exists := cfg.Pools[name]; !exists {
+			removed[name] = pool
+		}
+	}
+
+	return removed
+}
+
+// stopPools stops and forgets the pools, cancelling any running jobs
+func (runner *Runner) stopPools(pools map[string]*executor.Pool) {
+	for name, pool := range pools {
+		delete(runner.pools, name)
+		pool.RemoveMetrics()
+
+		go func(name string, pool *executor.Pool) {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownDelay)
+			defer cancel()
+
+			err := pool.Stop(ctx)
+			if err != nil {
+				runner.logger.WithError(err).WithField("pool", name).Error("Executors failed to shutdown")
+			}
+		}(name, pool)
+	}
+}
+
+// listenWebhook starts, moves or stops the webhook server so that it
+// listens on address. An empty address stops the server
+func (runner *Runner) listenWebhook(address string) error {
+	if address == runner.webhookAddress {
+		return nil
+	}
+
+	if address == "" {
+		err := runner.webhook.Stop(context.Background())
+		if err != nil {
+			return err
+		}
+	} else {
+		err := runner.webhook.Listen(address)
+		if err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+	}
+
+	runner.webhookAddress = address
+	return nil
+}
+
+// restoreListener moves a server back to address after a failed apply.
+// The previous address was bound until it was moved, so this should not
+// fail, but if it does the server stays on the new address
+func (runner *Runner) restoreListener(name string, listen func(string) error, address string) {
+	err := listen(address)
+	if err != nil {
+		runner.logger.
+			WithError(err).
+			WithField("address", address).
+			Errorf("Failed to restore the %s server", name)
+	}
+}
+
+// listenMetrics starts, moves or stops the metrics server so that it
+// listens on address. An empty address stops the server
+func (runner *Runner) listenMetrics(address string) error {
+	if address == runner.metricsAddress {
+		return nil
+	}
+
+	if address == "" {
+		err := runner.metrics.Stop(context.Background())
+		if err != nil {
+			return err
+		}
+	} else {
+		err := runner.metrics.Listen(address)
+		if err != nil {
+			return fmt.Errorf("metrics: %w", err)
+		}
+	}
+
+	runner.metricsAddress = address
+	return nil
+}
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// testService is a service of a test config, that runs command on a
+// schedule that never fires during a test
+const testService = `
+  - name: %s
+    condition:
+      type: cron
+      config:
+        schedule: "0 0 0 1 1 *"
+    execute:
+      type: shell
+      config:
+        command: %q
+`
+
+// testRunner is a runner of a config file in a temporary directory,
+// written by configure
+type testRunner struct {
+	*Runner
+	t    *testing.T
+	dir  string
+	path string
+}
+
+// newTestRunner constructs a runner without a config, which is shut down
+// once the test ends
+func newTestRunner(t *testing.T) *testRunner {
+	t.Helper()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "saucisson.yml")
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	//Logs are written to a file once the config is applied
+	runner := &testRunner{
+		Runner: newRunner(logger, path, Options{LogOutput: filepath.Join(dir, "saucisson.log")}),
+		t:      t,
+		dir:    dir,
+		path:   path,
+	}
+	t.Cleanup(runner.shutdown)
+
+	return runner
+}
+
+// configure writes a config of the services, named by the command they
+// run, with extra appended as is, then reloads it
+func (runner *testRunner) configure(services map[string]string, extra string) {
+	runner.t.Helper()
+
+	var cfg strings.Builder
+	fmt.Fprintf(&cfg, "history:\n  dir: %q\n", filepath.Join(runner.dir, "history"))
+	cfg.WriteString(extra)
+	cfg.WriteString("services:\n")
+	for name, command := range services {
+		fmt.Fprintf(&cfg, testService, name, command)
+	}
+
+	assert.NoError(runner.t, os.WriteFile(runner.path, []byte(cfg.String()), 0600))
+	runner.reload()
+}
+
+// registered returns the services that are registered, by name
+func (runner *testRunner) registered() map[string]*service {
+	runner.servicesMu.Lock()
+	defer runner.servicesMu.Unlock()
+
+	services := make(map[string]*service, len(runner.services))
+	for name, svc := range runner.services {
+		services[name] = svc
+	}
+	return services
+}
+
+// names returns the names of services in order
+func names(services map[string]*service) []string {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestReload(t *testing.T) {
+	runner := newTestRunner(t)
+
+	runner.configure(map[string]string{"one": "echo one", "two": "echo two"}, "")
+	before := runner.registered()
+	assert.Equal(t, []string{"one", "two"}, names(before))
+
+	runner.configure(map[string]string{"one": "echo one", "two": "echo changed", "three": "echo three"}, "")
+	after := runner.registered()
+	assert.Equal(t, []string{"one", "three", "two"}, names(after))
+
+	//Unchanged services keep their registration, changed ones are replaced
+	assert.Same(t, before["one"], after["one"])
+	assert.NotSame(t, before["two"], after["two"])
+	assert.Equal(t, 3, runner.cron.Len())
+
+	runner.configure(map[string]string{"one": "echo one"}, "")
+	assert.Equal(t, []string{"one"}, names(runner.registered()))
+	assert.Equal(t, 1, runner.cron.Len())
+}
+
+func TestReloadRejected(t *testing.T) {
+	runner := newTestRunner(t)
+
+	runner.configure(map[string]string{"one": "echo one", "two": "echo two"}, "")
+	before := runner.registered()
+
+	//An invalid config is rejected before anything is registered
+	runner.configure(map[string]string{"one": "echo one", "two": "{{ .Missing"}, "")
+	assert.Equal(t, before, runner.registered())
+
+	//The webhook address is taken, so the services registered by the
+	//reload are deregistered again
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	webhook := fmt.Sprintf("webhook:\n  address: %q\n", listener.Addr().String())
+	runner.configure(map[string]string{"one": "echo one", "two": "echo changed", "three": "echo three"}, webhook)
+
+	assert.Equal(t, before, runner.registered())
+	assert.Equal(t, 2, runner.cron.Len())
+	assert.Equal(t, "", runner.webhookAddress)
+}
@@ -2,14 +2,23 @@ package runner
 
 import (
 	"context"
-	"net/http"
+	"errors"
+	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/control"
+	"github.com/mickyco94/saucisson/internal/event"
 	"github.com/mickyco94/saucisson/internal/executor"
+	"github.com/mickyco94/saucisson/internal/history"
+	"github.com/mickyco94/saucisson/internal/logging"
+	"github.com/mickyco94/saucisson/internal/metrics"
 	"github.com/mickyco94/saucisson/internal/watcher"
 	"github.com/sirupsen/logrus"
 )
@@ -20,80 +29,112 @@ import (
 // interpreting the provided configuration and coordinating those
 // dependencies.
 type Runner struct {
-	logger logrus.FieldLogger
+	logger       logrus.FieldLogger
+	templatePath string
+
+	//baseLogger is logger, its output is configured by the logging config
+	//and overridden by logOverrides. logging is the config of logOutput
+	baseLogger   *logrus.Logger
+	logOverrides config.Logging
+	logging      config.Logging
+	logOutput    *logging.Output
 
 	cron    *watcher.Cron
 	file    *watcher.File
 	process *watcher.Process
+	webhook *watcher.Webhook
 	pool    *executor.Pool
+	//processes are those started by spawn executors
+	processes *executor.Processes
+	control   *control.Server
+	history   *history.Store
+	metrics   *metrics.Server
+
+	started time.Time
+
+	//webhookAddress and metricsAddress are the addresses the webhook
+	//and metrics servers are listening on, if any
+	webhookAddress string
+	metricsAddress string
+
+	//servicesMu guards services, pools and the server addresses
+	servicesMu sync.Mutex
+	services   map[string]*service
+	//pools are the named executor pools, runner.pool is the shared pool
+	pools map[string]*executor.Pool
+
+	//stateMu guards the runtime state of services and watchers,
+	//which is kept by name so that it survives a reload
+	stateMu  sync.Mutex
+	paused   map[string]bool
+	results  map[string]*control.Result
+	failures map[string]error
+}
+
+// Options are the behavioural switches of the run command
+type Options struct {
+	// WatchConfig reloads the config whenever the config file is updated,
+	// in addition to reloading on SIGHUP
+	WatchConfig bool
+
+	// Socket is the path of the control socket, if empty the
+	// control socket is disabled
+	Socket string
+
+	// LogLevel, LogFormat and LogOutput override the logging config, if set
+	LogLevel  string
+	LogFormat string
+	LogOutput string
 }
 
 // Run constructs and invokes a runner using the provided templatePath
 // to retrieve the config that drives runner.
 // Run will block and execute until a SIGINT signal is received from the os
 // at which point Run will attempt to gracefully shutdown its dependencies.
-func Run(templatePath string) error {
+// A SIGHUP instructs Run to reload the config, see Runner.reload
+func Run(templatePath string, opts Options) error {
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
 
-	formatter := &logrus.JSONFormatter{
-		PrettyPrint: true,
-	}
-
-	logger := logrus.New()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 
-	logger.SetFormatter(formatter)
-	logger.SetLevel(logrus.DebugLevel)
+	runner := newRunner(logrus.New(), templatePath, opts)
 
-	runner := &Runner{
-		logger:  logger,
-		pool:    executor.NewPool(logger, executor.DefaultPoolSize),
-		cron:    watcher.NewCron(),
-		process: watcher.NewProcess(logger),
-		file:    watcher.NewFile(logger),
-	}
-
-	file, err := os.Open(templatePath)
+	cfg, err := load(templatePath)
 	if err != nil {
 		return err
 	}
 
-	cfg := &config.Raw{}
-
-	err = cfg.Parse(file)
+	//The socket is claimed first, so that a second daemon using the
+	//same socket exits before starting any services
+	if opts.Socket != "" {
+		err = runner.control.Listen(opts.Socket)
+		if err != nil {
+			return err
+		}
+	}
 
+	err = runner.apply(cfg)
 	if err != nil {
+		runner.control.Stop(context.Background())
 		return err
 	}
+	defer runner.shutdown()
 
-	for _, s := range cfg.Services {
-		def := runner.construct(s)
-		serviceName := s.Name
-		queueJob := func() {
-			runner.pool.Enqueue(executor.Job{
-				Service:  serviceName,
-				Executor: def.executor.Execute,
-			})
+	//reload is buffered so that bursts of reload requests are coalesced
+	reload := make(chan struct{}, 1)
+	requestReload := func() {
+		select {
+		case reload <- struct{}{}:
+		default:
 		}
-		if def.file != nil {
-			err := runner.file.HandleFunc(def.file, queueJob)
-
-			if err != nil {
-				panic(err)
-			}
-		} else if def.cron != nil {
-			err := runner.cron.HandleFunc(def.cron, queueJob)
-			if err != nil {
-				panic(err)
-			}
-		} else if def.process != nil {
-			runner.process.HandleFunc(def.process, queueJob)
-		} else {
-			runner.logger.
-				WithField("svc", s.Name).
-				Panic("Has no condition specified")
+	}
 
-			return nil
+	if opts.WatchConfig {
+		err := runner.watchConfig(requestReload)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -102,6 +143,7 @@ func Run(templatePath string) error {
 	go func() {
 		err := runner.file.Run(time.Millisecond * 100)
 		if err != nil {
+			runner.fail("file", err)
 			close(fileProccessorClosedChan)
 		}
 	}()
@@ -113,24 +155,145 @@ func Run(templatePath string) error {
 	go func() {
 		err := runner.process.Run()
 		if err != nil {
+			runner.fail("process", err)
 			close(processRunnerClosedChan)
 		}
 	}()
 
-	defer runner.shutdown()
+	for {
+		select {
+		case <-fileProccessorClosedChan:
+			runner.logger.Error("File service failed unexpectedly, shutting down")
+			return nil
+		case <-sig:
+			runner.logger.Debug("Received SIGINT, shutting down")
+			return nil
+		case <-processRunnerClosedChan:
+			runner.logger.Error("Process service failed unexpectedly, shutting down")
+			return nil
+		case <-hup:
+			runner.logger.Debug("Received SIGHUP, reloading config")
+			requestReload()
+		case <-reload:
+			runner.reload()
+		}
+	}
+}
+
+// newRunner constructs a runner of the config at templatePath, with its
+// watchers, executors and servers, none of which are started
+func newRunner(logger *logrus.Logger, templatePath string, opts Options) *Runner {
+	runner := &Runner{
+		logger:       logger,
+		templatePath: templatePath,
+		pool:         executor.NewPool(logger, config.DefaultPool, executor.DefaultPoolSize),
+		cron:         watcher.NewCron(),
+		process:      watcher.NewProcess(logger),
+		file:         watcher.NewFile(logger),
+		webhook:      watcher.NewWebhook(logger),
+		processes:    executor.NewProcesses(logger),
+		history:      history.NewStore(logger, config.History{}),
+		metrics:      metrics.NewServer(logger, metrics.Default),
+		services:     make(map[string]*service),
+		pools:        make(map[string]*executor.Pool),
+		started:      time.Now(),
+		paused:       make(map[string]bool),
+		results:      make(map[string]*control.Result),
+		failures:     make(map[string]error),
+		baseLogger:   logger,
+		logOverrides: config.Logging{
+			Level:  opts.LogLevel,
+			Format: config.LogFormat(opts.LogFormat),
+			Output: opts.LogOutput,
+		},
+	}
+	runner.control = control.NewServer(logger, runner)
+
+	return runner
+}
+
+// watchConfig calls reload when the config file is written. Editors that save
+// by writing a new file and renaming it over the config are seen as a create
+// or rename in its directory
+func (runner *Runner) watchConfig(reload func()) error {
+	path, err := filepath.Abs(runner.templatePath)
+	if err != nil {
+		return err
+	}
+
+	handler := func(ev event.Event) {
+		if ev.Path == path {
+			reload()
+		}
+	}
 
-	select {
-	case <-fileProccessorClosedChan:
-		runner.logger.Error("File service failed unexpectedly, shutting down")
-	case <-sig:
-		runner.logger.Debug("Received SIGINT, shutting down")
-	case <-processRunnerClosedChan:
-		runner.logger.Error("Process service failed unexpectedly, shutting down")
+	conditions := []*config.File{
+		{Path: path, Operation: config.Update},
+		{Path: filepath.Dir(path), Operation: config.Create},
+		{Path: filepath.Dir(path), Operation: config.Rename},
+	}
+
+	for _, condition := range conditions {
+		_, err := runner.file.HandleFunc(condition, handler)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// Validate parses the configuration at templatePath and checks that every
+// service can be constructed, without starting any watchers or executors.
+// All problems found are returned together as config.Errors.
+func Validate(templatePath string) error {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	runner := &Runner{logger: logger}
+
+	cfg, err := load(templatePath)
+
+	var errs config.Errors
+	if err != nil && !errors.As(err, &errs) {
+		return err
+	}
+
+	// Structural problems do not prevent the services themselves being checked
+	_, err = runner.constructAll(cfg)
+	if err != nil {
+		errs = append(errs, err.(config.Errors)...)
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Line < errs[j].Line
+	})
+
+	return errs.Err()
+}
+
+// load reads and parses the configuration file at path.
+// If the file could be decoded but has problems, the config is returned
+// alongside config.Errors
+func load(path string) (*config.Raw, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cfg := &config.Raw{}
+
+	err = cfg.Parse(file)
+
+	var errs config.Errors
+	if err != nil && !errors.As(err, &errs) {
+		return nil, err
+	}
+
+	return cfg, err
+}
+
 // shutdownDelay is the maximum time limit dependent services have to exit.
 // If this time limit is exceeded the application exits without properly
 // terminating those dependencies.
@@ -172,6 +335,46 @@ func (runner *Runner) shutdown() {
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		err := runner.webhook.Stop(shutdownCtx)
+		if err != nil {
+			runner.logger.WithError(err).Error("Webhook server failed to shutdown")
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		err := runner.metrics.Stop(shutdownCtx)
+		if err != nil {
+			runner.logger.WithError(err).Error("Metrics server failed to shutdown")
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		err := runner.control.Stop(shutdownCtx)
+		if err != nil {
+			runner.logger.WithError(err).Error("Control socket failed to shutdown")
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		err := runner.processes.Stop(shutdownCtx)
+		if err != nil {
+			runner.logger.WithError(err).Error("Spawned processes failed to stop, killed")
+		}
+	}()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -182,50 +385,24 @@ func (runner *Runner) shutdown() {
 		}
 	}()
 
-	wg.Wait()
-}
+	runner.servicesMu.Lock()
+	for name, pool := range runner.pools {
+		wg.Add(1)
+		go func(name string, pool *executor.Pool) {
+			defer wg.Done()
 
-// definition can have any number of conditions of different types
-// That all need to be registered
-// For all of those conditions, each executor needs to be registered
-type definition struct {
-	cron    *config.Cron
-	file    *config.File
-	process *config.Process
+			err := pool.Stop(shutdownCtx)
+			if err != nil {
+				runner.logger.WithError(err).WithField("pool", name).Error("Executors failed to shutdown")
+			}
+		}(name, pool)
+	}
+	runner.servicesMu.Unlock()
 
-	executor executor.Executor
-}
+	wg.Wait()
+
+	//Jobs have stopped, so the history is no longer appended to
+	runner.history.Wait()
 
-// construct constructs an actual implementation of a Service from
-// a specification
-func (runner *Runner) construct(spec config.ServiceSpec) *definition {
-	def := &definition{}
-
-	switch spec.Condition.Type {
-	case config.CronKey:
-		cronConf := &config.Cron{}
-		spec.Condition.Config.Decode(cronConf)
-		def.cron = cronConf
-	case config.FileKey:
-		fileConf := &config.File{}
-		spec.Condition.Config.Decode(fileConf)
-		def.file = fileConf
-	case config.Processkey:
-		processConf := &config.Process{}
-		spec.Condition.Config.Decode(processConf)
-		def.process = processConf
-	}
-
-	switch spec.Execute.Type {
-	case "shell":
-		shell := executor.NewShell(runner.logger)
-		spec.Execute.Config.Decode(shell)
-		def.executor = shell
-	case "http":
-		http := executor.NewHttp(runner.logger, *http.DefaultClient) //TODO: This should be more specific..
-		spec.Execute.Config.Decode(http)
-		def.executor = http
-	}
-
-	return def
+	runner.closeLogging()
 }
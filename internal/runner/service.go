@@ -0,0 +1,366 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/executor"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// definition can have any number of conditions of different types
+// That all need to be registered
+// For all of those conditions, each executor needs to be registered
+type definition struct {
+	name string
+	//fingerprint identifies the spec the definition was constructed from,
+	//two definitions with the same fingerprint are interchangeable
+	fingerprint string
+
+	//conditions are independent, the executor is run whenever any is satisfied
+	conditions []*condition
+
+	executor executor.Executor
+	//executorType is the type of executor, or pipeline, for display
+	executorType string
+
+	concurrency config.Concurrency
+	//pool is the name of the executor pool, empty for the shared pool
+	pool string
+
+	//threshold, debounce and throttle limit how often conditions trigger
+	//the service, each is disabled if zero. maxWait bounds debounce
+	threshold *config.Threshold
+	debounce  time.Duration
+	maxWait   time.Duration
+	throttle  time.Duration
+}
+
+// condition is a node in the tree of conditions of a service.
+// Leaves have one of cron, file, process or webhook set and are registered with
+// the corresponding watcher, branches combine their children according to kind
+type condition struct {
+	cron    *config.Cron
+	file    *config.File
+	process *config.Process
+	webhook *config.Webhook
+
+	kind     config.Condition
+	window   time.Duration
+	children []*condition
+}
+
+// builder constructs definitions from the services of a config, it holds
+// the dependencies that are shared by every service in that config
+type builder struct {
+	logger    logrus.FieldLogger
+	templates *executor.Templates
+	processes *executor.Processes
+	//shared is serialised into the fingerprint of every service, so that
+	//services are reconstructed when shared config changes
+	shared string
+	//webhooks is true if the webhook server is configured to listen
+	webhooks bool
+	//pools are the named executor pools
+	pools map[string]config.Pool
+}
+
+// constructAll constructs a definition for every service in cfg, collecting
+// the problems of every service rather than stopping at the first
+func (runner *Runner) constructAll(cfg *config.Raw) ([]*definition, error) {
+	var errs config.Errors
+
+	b := &builder{
+		logger:    runner.logger,
+		templates: executor.NewTemplates(cfg.Vars),
+		processes: runner.processes,
+		shared:    fingerprint(cfg.Vars),
+		webhooks:  cfg.Webhook.Address != "",
+		pools:     cfg.Pools,
+	}
+
+	definitions := make([]*definition, 0, len(cfg.Services))
+
+	for _, spec := range cfg.Services {
+		def, specErrs := b.construct(spec)
+		errs = append(errs, specErrs...)
+		definitions = append(definitions, def)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return definitions, nil
+}
+
+// construct constructs an actual implementation of a Service from
+// a specification
+func (b *builder) construct(spec config.ServiceSpec) (*definition, config.Errors) {
+	def := &definition{
+		name:        spec.Name,
+		fingerprint: b.shared + fingerprint(spec),
+		concurrency: spec.Concurrency,
+		pool:        spec.Pool,
+		threshold:   spec.Threshold,
+		debounce:    spec.Debounce,
+		throttle:    spec.Throttle,
+		maxWait:     spec.DebounceMaxWait,
+	}
+
+	var errs config.Errors
+
+	for _, err := range spec.Concurrency.Validate() {
+		errs = append(errs, spec.Errorf("concurrency: %s", err.Error()))
+	}
+
+	if spec.Threshold != nil {
+		for _, err := range spec.Threshold.Validate() {
+			errs = append(errs, spec.Errorf("threshold: %s", err.Error()))
+		}
+	}
+
+	if spec.Debounce < 0 {
+		errs = append(errs, spec.Errorf("debounce must not be negative"))
+	}
+
+	if spec.DebounceMaxWait < 0 {
+		errs = append(errs, spec.Errorf("debounce_max_wait must not be negative"))
+	} else if spec.DebounceMaxWait > 0 && spec.DebounceMaxWait < spec.Debounce {
+		errs = append(errs, spec.Errorf("debounce_max_wait must not be less than debounce"))
+	}
+
+	if spec.Throttle < 0 {
+		errs = append(errs, spec.Errorf("throttle must not be negative"))
+	}
+
+	if _, exists := b.pools[spec.Pool]; spec.Pool != "" && !exists {
+		errs = append(errs, spec.Errorf("pool %q is not defined in pools", spec.Pool))
+	}
+
+	switch {
+	case !spec.Condition.IsZero() && len(spec.Conditions) > 0:
+		errs = append(errs, spec.Errorf("only one of condition or conditions can be specified"))
+	case !spec.Condition.IsZero():
+		cond, condErrs := b.constructCondition(&spec.Condition)
+		errs = append(errs, condErrs...)
+		def.conditions = []*condition{cond}
+	case len(spec.Conditions) > 0:
+		for i := range spec.Conditions {
+			cond, condErrs := b.constructCondition(&spec.Conditions[i])
+			errs = append(errs, condErrs...)
+			def.conditions = append(def.conditions, cond)
+		}
+	default:
+		errs = append(errs, spec.Errorf("condition is required"))
+	}
+
+	switch {
+	case !spec.Execute.IsZero() && len(spec.Steps) > 0:
+		errs = append(errs, spec.Errorf("only one of execute or steps can be specified"))
+	case spec.Execute.IsZero() && len(spec.Steps) == 0:
+		errs = append(errs, spec.Errorf("execute or steps is required"))
+	case len(spec.Steps) == 0 && len(spec.OnFailure)+len(spec.Finally) == 0:
+		exec, execErrs := b.constructExecutor(&spec.Execute)
+		errs = append(errs, execErrs...)
+		def.executor = exec
+		def.executorType = string(spec.Execute.Type)
+	default:
+		pipeline, pipelineErrs := b.constructPipeline(spec)
+		errs = append(errs, pipelineErrs...)
+		def.executor = pipeline
+		def.executorType = "pipeline"
+	}
+
+	return def, errs
+}
+
+// constructExecutor constructs an executor from a specification,
+// decorated with its retry policy if any
+func (b *builder) constructExecutor(spec *config.ComponentSpec) (executor.Executor, config.Errors) {
+	exec, errs := b.constructBaseExecutor(spec)
+	errs = append(errs, spec.ValidateRetry()...)
+
+	if spec.Retry == nil || exec == nil {
+		return exec, errs
+	}
+
+	return executor.NewRetry(b.logger, exec, *spec.Retry), errs
+}
+
+// constructBaseExecutor constructs the executor of the type of the specification
+func (b *builder) constructBaseExecutor(spec *config.ComponentSpec) (executor.Executor, config.Errors) {
+	switch config.Executor(spec.Type) {
+	case config.ShellKey:
+		shell := executor.NewShell(b.logger, b.templates)
+		return shell, spec.Decode(shell)
+	case config.HttpKey:
+		http := executor.NewHttp(b.logger, b.templates)
+		return http, spec.Decode(http)
+	case config.ExecKey:
+		exec := executor.NewExec(b.logger, b.templates)
+		return exec, spec.Decode(exec)
+	case config.SpawnKey:
+		spawn := executor.NewSpawn(b.logger, b.templates, b.processes)
+		return spawn, spec.Decode(spawn)
+	default:
+		return nil, config.Errors{spec.TypeErrorf("unknown executor type %q", spec.Type)}
+	}
+}
+
+// constructPipeline constructs a pipeline from the steps of a service.
+// If the service has a single execute rather than steps, it is the only step.
+// Steps can only refer to the outcome of steps that come before them
+func (b *builder) constructPipeline(spec config.ServiceSpec) (*executor.Pipeline, config.Errors) {
+	pipeline := executor.NewPipeline(b.logger.WithField("svc", spec.Name))
+
+	var errs config.Errors
+
+	//defined tracks the names of steps that have been constructed so far
+	defined := make(map[string]struct{})
+
+	if !spec.Execute.IsZero() {
+		exec, execErrs := b.constructExecutor(&spec.Execute)
+		errs = append(errs, execErrs...)
+		pipeline.Steps = []executor.Step{{Name: "execute", Executor: exec}}
+		defined["execute"] = struct{}{}
+	}
+
+	constructSteps := func(section string, specs []config.StepSpec) []executor.Step {
+		steps := make([]executor.Step, 0, len(specs))
+
+		for i := range specs {
+			stepSpec := &specs[i]
+
+			step := executor.Step{
+				Name:            stepSpec.Name,
+				ContinueOnError: stepSpec.ContinueOnError,
+			}
+
+			if step.Name == "" {
+				step.Name = fmt.Sprintf("%s[%d]", section, i)
+			}
+
+			if _, exists := defined[step.Name]; exists {
+				errs = append(errs, stepSpec.Errorf("step name %q is already in use", step.Name))
+			}
+
+			if stepSpec.When != nil {
+				for _, err := range stepSpec.When.Validate() {
+					errs = append(errs, stepSpec.Errorf("when: %s", err.Error()))
+				}
+
+				if _, exists := defined[stepSpec.When.Step]; stepSpec.When.Step != "" && !exists {
+					errs = append(errs, stepSpec.Errorf("when: step %q is not defined before this step", stepSpec.When.Step))
+				}
+
+				step.When = &executor.When{
+					Step:     stepSpec.When.Step,
+					Status:   stepSpec.When.Status,
+					ExitCode: stepSpec.When.ExitCode,
+				}
+			}
+
+			component := stepSpec.Component()
+			exec, execErrs := b.constructExecutor(&component)
+			errs = append(errs, execErrs...)
+			step.Executor = exec
+
+			defined[step.Name] = struct{}{}
+			steps = append(steps, step)
+		}
+
+		return steps
+	}
+
+	if len(spec.Steps) > 0 {
+		pipeline.Steps = constructSteps("steps", spec.Steps)
+	}
+	pipeline.OnFailure = constructSteps("on_failure", spec.OnFailure)
+	pipeline.Finally = constructSteps("finally", spec.Finally)
+
+	return pipeline, errs
+}
+
+// constructCondition constructs the condition tree from a specification,
+// recursing into the children of composite conditions
+func (b *builder) constructCondition(spec *config.ComponentSpec) (*condition, config.Errors) {
+	cond := &condition{kind: spec.Type}
+
+	var errs config.Errors
+	var children []config.ComponentSpec
+
+	switch spec.Type {
+	case config.CronKey:
+		cond.cron = &config.Cron{}
+		errs = spec.Decode(cond.cron)
+	case config.FileKey:
+		cond.file = &config.File{}
+		errs = spec.Decode(cond.file)
+	case config.Processkey:
+		cond.process = &config.Process{}
+		errs = spec.Decode(cond.process)
+	case config.WebhookKey:
+		cond.webhook = &config.Webhook{}
+		errs = spec.Decode(cond.webhook)
+		if !b.webhooks {
+			errs = append(errs, spec.TypeErrorf("webhook conditions require webhook.address to be set"))
+		}
+	case config.AnyKey:
+		anyConf := &config.Any{}
+		errs = spec.Decode(anyConf)
+		children = anyConf.Conditions
+	case config.AllKey:
+		allConf := &config.All{}
+		errs = spec.Decode(allConf)
+		cond.window = allConf.Window
+		children = allConf.Conditions
+	case config.SequenceKey:
+		sequenceConf := &config.Sequence{}
+		errs = spec.Decode(sequenceConf)
+		cond.window = sequenceConf.Window
+		children = sequenceConf.Conditions
+	default:
+		errs = config.Errors{spec.TypeErrorf("unknown condition type %q", spec.Type)}
+	}
+
+	if spec.Retry != nil {
+		errs = append(errs, spec.Errorf("retry can only be used with executors"))
+	}
+
+	for i := range children {
+		child, childErrs := b.constructCondition(&children[i])
+		errs = append(errs, childErrs...)
+		cond.children = append(cond.children, child)
+	}
+
+	return cond, errs
+}
+
+// String describes the condition tree, e.g. all(file, cron)
+func (cond *condition) String() string {
+	if len(cond.children) == 0 {
+		return string(cond.kind)
+	}
+
+	children := make([]string, len(cond.children))
+	for i, child := range cond.children {
+		children[i] = child.String()
+	}
+
+	return fmt.Sprintf("%s(%s)", cond.kind, strings.Join(children, ", "))
+}
+
+// fingerprint serialises a spec so that it can be compared with
+// other specs, ignoring the position of the spec in the file
+func fingerprint(spec any) string {
+	out, err := yaml.Marshal(spec)
+	if err != nil {
+		// Unreachable for a spec that was decoded from YAML, treat as always changed
+		return ""
+	}
+	return string(out)
+}
@@ -0,0 +1,132 @@
+package watcher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/mickyco94/saucisson/internal/executor"
+)
+
+// All combines a number of child conditions, invoking its handler once
+// every child has fired within the window. Each child is represented by
+// the handler returned from Child, which should be registered with the
+// watcher for that child.
+// Once the handler has been invoked every child must fire again before
+// it is next invoked.
+type All struct {
+	mu      sync.Mutex
+	window  time.Duration
+	fired   []time.Time
+	handler func(event.Event) error
+	now     func() time.Time
+}
+
+// NewAll constructs an All with size children
+func NewAll(size int, window time.Duration, handler func(event.Event) error) *All {
+	return &All{
+		window:  window,
+		fired:   make([]time.Time, size),
+		handler: handler,
+		now:     time.Now, //Setting this here supports mocking
+	}
+}
+
+// Child returns the handler for the child at index i.
+// The event of the child that completes the condition is passed on,
+// along with any error from the handler. Other events are reported as not run
+func (all *All) Child(i int) func(event.Event) error {
+	return func(ev event.Event) error {
+		if all.fire(i) {
+			return all.handler(ev)
+		}
+		return &executor.NotRunError{Reason: "waiting for other conditions"}
+	}
+}
+
+// fire records child i as having fired, returning true if every
+// child has now fired within the window
+func (all *All) fire(i int) bool {
+	all.mu.Lock()
+	defer all.mu.Unlock()
+
+	now := all.now()
+	all.fired[i] = now
+
+	for _, fired := range all.fired {
+		if fired.IsZero() || now.Sub(fired) > all.window {
+			return false
+		}
+	}
+
+	for j := range all.fired {
+		all.fired[j] = time.Time{}
+	}
+
+	return true
+}
+
+// Sequence combines a number of child conditions, invoking its handler once
+// every child has fired in order, with the first and last child firing within
+// the window. Children that fire out of order are ignored, except for the first
+// child which always restarts the sequence.
+type Sequence struct {
+	mu      sync.Mutex
+	window  time.Duration
+	size    int
+	next    int
+	started time.Time
+	handler func(event.Event) error
+	now     func() time.Time
+}
+
+// NewSequence constructs a Sequence with size children
+func NewSequence(size int, window time.Duration, handler func(event.Event) error) *Sequence {
+	return &Sequence{
+		window:  window,
+		size:    size,
+		handler: handler,
+		now:     time.Now, //Setting this here supports mocking
+	}
+}
+
+// Child returns the handler for the child at index i.
+// The event of the last child in the sequence is passed on,
+// along with any error from the handler. Other events are reported as not run
+func (sequence *Sequence) Child(i int) func(event.Event) error {
+	return func(ev event.Event) error {
+		if sequence.fire(i) {
+			return sequence.handler(ev)
+		}
+		return &executor.NotRunError{Reason: "waiting for other conditions in sequence"}
+	}
+}
+
+// fire advances the sequence if child i is the next expected child,
+// returning true when the sequence is complete
+func (sequence *Sequence) fire(i int) bool {
+	sequence.mu.Lock()
+	defer sequence.mu.Unlock()
+
+	now := sequence.now()
+
+	if sequence.next > 0 && now.Sub(sequence.started) > sequence.window {
+		sequence.next = 0
+	}
+
+	if i == 0 {
+		sequence.started = now
+		sequence.next = 1
+	} else if i == sequence.next {
+		sequence.next++
+	} else {
+		return false
+	}
+
+	if sequence.next < sequence.size {
+		return false
+	}
+
+	sequence.next = 0
+	return true
+}
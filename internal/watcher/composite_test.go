@@ -0,0 +1,92 @@
+package watcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/stretchr/testify/assert"
+)
+
+// clock is a manually advanced time source
+type clock struct {
+	current time.Time
+}
+
+func (c *clock) now() time.Time { return c.current }
+
+func (c *clock) advance(d time.Duration) { c.current = c.current.Add(d) }
+
+func TestAllFiresOnceEveryChildHasFired(t *testing.T) {
+	fired := 0
+	c := &clock{current: time.Now()}
+
+	all := NewAll(2, time.Minute, func(event.Event) error { fired++; return nil })
+	all.now = c.now
+
+	all.Child(0)(event.Event{})
+	all.Child(0)(event.Event{})
+	assert.Equal(t, 0, fired)
+
+	all.Child(1)(event.Event{})
+	assert.Equal(t, 1, fired)
+
+	//Children must all fire again after the handler is invoked
+	all.Child(1)(event.Event{})
+	assert.Equal(t, 1, fired)
+}
+
+func TestAllOutsideWindow(t *testing.T) {
+	fired := 0
+	c := &clock{current: time.Now()}
+
+	all := NewAll(2, time.Minute, func(event.Event) error { fired++; return nil })
+	all.now = c.now
+
+	all.Child(0)(event.Event{})
+	c.advance(2 * time.Minute)
+	all.Child(1)(event.Event{})
+	assert.Equal(t, 0, fired)
+
+	c.advance(time.Second)
+	all.Child(0)(event.Event{})
+	assert.Equal(t, 1, fired)
+}
+
+func TestSequenceInOrder(t *testing.T) {
+	fired := 0
+	c := &clock{current: time.Now()}
+
+	sequence := NewSequence(3, time.Minute, func(event.Event) error { fired++; return nil })
+	sequence.now = c.now
+
+	sequence.Child(0)(event.Event{})
+	sequence.Child(2)(event.Event{})
+	assert.Equal(t, 0, fired)
+
+	sequence.Child(1)(event.Event{})
+	sequence.Child(2)(event.Event{})
+	assert.Equal(t, 1, fired)
+
+	sequence.Child(1)(event.Event{})
+	sequence.Child(2)(event.Event{})
+	assert.Equal(t, 1, fired)
+}
+
+func TestSequenceOutsideWindow(t *testing.T) {
+	fired := 0
+	c := &clock{current: time.Now()}
+
+	sequence := NewSequence(2, time.Minute, func(event.Event) error { fired++; return nil })
+	sequence.now = c.now
+
+	sequence.Child(0)(event.Event{})
+	c.advance(2 * time.Minute)
+	sequence.Child(1)(event.Event{})
+	assert.Equal(t, 0, fired)
+
+	sequence.Child(0)(event.Event{})
+	c.advance(time.Second)
+	sequence.Child(1)(event.Event{})
+	assert.Equal(t, 1, fired)
+}
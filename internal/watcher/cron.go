@@ -2,8 +2,11 @@ package watcher
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
 	internal "github.com/robfig/cron/v3"
 )
 
@@ -12,19 +15,65 @@ import (
 // widely throughout the architecture
 type Cron struct {
 	inner *internal.Cron
+
+	entriesMu sync.Mutex
+	entries   map[ID]internal.EntryID
 }
 
 // NewCron constructs a new cron schedule watcher
 func NewCron() *Cron {
 	return &Cron{
-		inner: internal.New(internal.WithSeconds()),
+		inner:   internal.New(internal.WithSeconds()),
+		entries: make(map[ID]internal.EntryID),
 	}
 }
 
 // HandleFunc registers a function to be executed when the provided condition is met.
-func (cron *Cron) HandleFunc(condition *config.Cron, handler func()) error {
-	_, err := cron.inner.AddFunc(condition.Schedule, handler)
-	return err
+func (cron *Cron) HandleFunc(condition *config.Cron, handler func(event.Event)) (ID, error) {
+	entryID, err := cron.inner.AddFunc(condition.Schedule, func() {
+		ev := event.New(config.CronKey)
+		// Schedules have a resolution of a second and jobs are started as soon
+		// as they are due, so the due time is the start of the current second
+		scheduled := ev.Time.Truncate(time.Second)
+		ev.Scheduled = &scheduled
+
+		handler(ev)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	id := nextID()
+
+	cron.entriesMu.Lock()
+	cron.entries[id] = entryID
+	cron.entriesMu.Unlock()
+
+	return id, nil
+}
+
+// Remove deregisters the handler with the provided id, the handler
+// will not be invoked for any future schedules.
+// Removing an unknown id is a no-op
+func (cron *Cron) Remove(id ID) {
+	cron.entriesMu.Lock()
+	defer cron.entriesMu.Unlock()
+
+	entryID, exists := cron.entries[id]
+	if !exists {
+		return
+	}
+
+	cron.inner.Remove(entryID)
+	delete(cron.entries, id)
+}
+
+// Len returns the number of handlers that are registered
+func (cron *Cron) Len() int {
+	cron.entriesMu.Lock()
+	defer cron.entriesMu.Unlock()
+
+	return len(cron.entries)
 }
 
 // Run starts the cron watcher on its own goroutine
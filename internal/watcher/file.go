@@ -3,13 +3,14 @@ package watcher
 import (
 	"context"
 	"errors"
-	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
 	filewatcher "github.com/radovskyb/watcher"
 	"github.com/sirupsen/logrus"
 )
@@ -24,6 +25,21 @@ var operationMap = map[config.Operation]filewatcher.Op{
 	config.Update: filewatcher.Write,
 }
 
+// newFileEvent converts an event from the underlying watcher into an event.Event
+func newFileEvent(fileEvent filewatcher.Event) event.Event {
+	ev := event.New(config.FileKey)
+	ev.Path = fileEvent.Path
+	ev.OldPath = fileEvent.OldPath
+
+	for operation, op := range operationMap {
+		if op == fileEvent.Op {
+			ev.Op = operation
+		}
+	}
+
+	return ev
+}
+
 func NewFile(logger logrus.FieldLogger) *File {
 
 	watcher := filewatcher.New()
@@ -41,6 +57,7 @@ func NewFile(logger logrus.FieldLogger) *File {
 }
 
 type fileEntry struct {
+	id ID
 	//path is the full path of the file/directory being watched
 	path string
 	//dir is set to true if the specified entry is a watch for a directory
@@ -48,7 +65,7 @@ type fileEntry struct {
 	//op is the type of operations we are listening for
 	op filewatcher.Op
 	//handler will be executed when a match is found
-	handler func()
+	handler func(event.Event)
 }
 
 type File struct {
@@ -59,8 +76,9 @@ type File struct {
 
 	logger logrus.FieldLogger
 
-	entries []fileEntry
-	watcher *filewatcher.Watcher
+	entriesMu sync.RWMutex
+	entries   []fileEntry
+	watcher   *filewatcher.Watcher
 }
 
 func (file *File) Stop(ctx context.Context) error {
@@ -83,34 +101,93 @@ func (file *File) Stop(ctx context.Context) error {
 // HandleFunc registers the provided function to be executed, when the provided
 // condition has been satisfied.
 // An error is returned if the provided condition is not logically complete
-func (f *File) HandleFunc(condition *config.File, handler func()) error {
+func (f *File) HandleFunc(condition *config.File, handler func(event.Event)) (ID, error) {
+
+	//Events are reported with absolute paths
+	path, err := filepath.Abs(condition.Path)
+	if err != nil {
+		return 0, err
+	}
 
-	file, err := os.Stat(condition.Path)
+	file, err := os.Stat(path)
 
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if file != nil &&
 		!file.IsDir() &&
 		operationMap[condition.Operation] == filewatcher.Create {
-		return ErrWatchCreateExistingFile
+		return 0, ErrWatchCreateExistingFile
 	}
 
-	err = f.watcher.Add(condition.Path)
+	err = f.watcher.Add(path)
 
 	if err != nil {
-		return err
+		return 0, err
 	}
 
+	id := nextID()
+
+	f.entriesMu.Lock()
+	defer f.entriesMu.Unlock()
+
 	f.entries = append(f.entries, fileEntry{
-		path:    condition.Path,
+		id:      id,
+		path:    path,
 		dir:     file.IsDir(),
 		op:      operationMap[condition.Operation],
 		handler: handler,
 	})
 
-	return nil
+	return id, nil
+}
+
+// Remove deregisters the handler with the provided id. The path is no longer
+// watched once no remaining handlers watch it, a directory containing it or
+// a path within it, as the watcher forgets the contents of a directory along
+// with it, which would then be seen as created.
+// Removing an unknown id is a no-op
+func (f *File) Remove(id ID) {
+	f.entriesMu.Lock()
+	defer f.entriesMu.Unlock()
+
+	var removed *fileEntry
+
+	for i, entry := range f.entries {
+		if entry.id == id {
+			removed = &entry
+			f.entries = append(f.entries[:i], f.entries[i+1:]...)
+			break
+		}
+	}
+
+	if removed == nil {
+		return
+	}
+
+	for _, entry := range f.entries {
+		if within(entry.path, removed.path) || within(removed.path, entry.path) {
+			return
+		}
+	}
+
+	err := f.watcher.Remove(removed.path)
+	if err != nil {
+		f.logger.
+			WithError(err).
+			WithField("path", removed.path).
+			Warn("Failed to stop watching path")
+	}
+}
+
+// within reports whether path is dir or is within it
+func within(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
 
 func (entry fileEntry) matches(event filewatcher.Event) bool {
@@ -155,14 +232,26 @@ func (file *File) Run(pollingInterval time.Duration) error {
 					return
 				}
 
+				fileEvents.WithLabelValues(strings.ToLower(event.Op.String())).Inc()
+
+				//Handlers can block, so they are called without holding the lock
+				//that Remove needs
+				var matched []fileEntry
+				file.entriesMu.RLock()
 				for _, entry := range file.entries {
 					if entry.matches(event) {
-						entry.handler()
+						matched = append(matched, entry)
 					}
 				}
+				file.entriesMu.RUnlock()
+
+				for _, entry := range matched {
+					entry.handler(newFileEvent(event))
+				}
 
 			case err := <-file.watcher.Error:
-				log.Printf("error: %v\n", err)
+				fileErrors.Inc()
+				file.logger.WithError(err).Error("File watcher error")
 			}
 		}
 	}()
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
 	filewatcher "github.com/radovskyb/watcher"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -44,7 +45,7 @@ func TestDirectory(t *testing.T) {
 		Operation: config.Create,
 	}
 
-	listener.HandleFunc(condition, func() {
+	listener.HandleFunc(condition, func(event.Event) {
 		done <- struct{}{}
 	})
 
@@ -66,15 +67,15 @@ func TestRename(t *testing.T) {
 
 	listener := NewFile(logrus.New())
 
-	done := make(chan struct{})
+	done := make(chan event.Event)
 
 	condition := &config.File{
 		Path:      basePath,
 		Operation: config.Rename,
 	}
 
-	listener.HandleFunc(condition, func() {
-		done <- struct{}{}
+	listener.HandleFunc(condition, func(ev event.Event) {
+		done <- ev
 	})
 
 	go listener.Run(time.Millisecond * 100)
@@ -86,7 +87,11 @@ func TestRename(t *testing.T) {
 	select {
 	case <-time.After(time.Second):
 		t.Error("Timed out")
-	case <-done:
+	case ev := <-done:
+		assert.Equal(t, config.FileKey, ev.Condition)
+		assert.Equal(t, config.Rename, ev.Op)
+		assert.Equal(t, newPath, ev.Path)
+		assert.Equal(t, filePath, ev.OldPath)
 	}
 }
 
@@ -103,7 +108,7 @@ func TestFileAlreadyExists(t *testing.T) {
 		Operation: config.Create,
 	}
 
-	err := listener.HandleFunc(condition, func() {})
+	_, err := listener.HandleFunc(condition, func(event.Event) {})
 
 	assert.Error(t, err, ErrWatchCreateExistingFile)
 }
@@ -122,7 +127,7 @@ func TestRemoval(t *testing.T) {
 		Operation: config.Remove,
 	}
 
-	listener.HandleFunc(condition, func() {
+	listener.HandleFunc(condition, func(event.Event) {
 		done <- struct{}{}
 	})
 
@@ -155,11 +160,11 @@ func TestMultipleWatchersForSameFile(t *testing.T) {
 		Operation: config.Create,
 	}
 
-	listener.HandleFunc(condition, func() {
+	listener.HandleFunc(condition, func(event.Event) {
 		one <- struct{}{}
 	})
 
-	listener.HandleFunc(condition, func() {
+	listener.HandleFunc(condition, func(event.Event) {
 		two <- struct{}{}
 	})
 
@@ -180,6 +185,124 @@ func TestMultipleWatchersForSameFile(t *testing.T) {
 	}
 }
 
+func TestRemoveHandler(t *testing.T) {
+	basePath := setup()
+
+	listener := NewFile(logrus.New())
+
+	removed := make(chan struct{}, 1)
+	kept := make(chan struct{}, 1)
+
+	condition := &config.File{
+		Path:      basePath,
+		Operation: config.Create,
+	}
+
+	id, _ := listener.HandleFunc(condition, func(event.Event) {
+		removed <- struct{}{}
+	})
+
+	listener.HandleFunc(condition, func(event.Event) {
+		kept <- struct{}{}
+	})
+
+	listener.Remove(id)
+
+	go listener.Run(time.Millisecond * 100)
+
+	createDummyFile(basePath)
+
+	select {
+	case <-time.After(500 * time.Millisecond):
+		t.Error("timeout")
+	case <-kept:
+	}
+
+	assert.Len(t, removed, 0)
+}
+
+func TestRemoveOverlappingPaths(t *testing.T) {
+	basePath := setup()
+	subPath := path.Join(basePath, "sub")
+	assert.NoError(t, os.Mkdir(subPath, 0755))
+	createDummyFile(subPath)
+	filePath := createDummyFile(basePath)
+
+	listener := NewFile(logrus.New())
+
+	events := make(chan string, 10)
+	handler := func(ev event.Event) {
+		events <- ev.Path
+	}
+
+	dir, _ := listener.HandleFunc(&config.File{Path: basePath, Operation: config.Create}, handler)
+	file, _ := listener.HandleFunc(&config.File{Path: filePath, Operation: config.Update}, handler)
+	listener.HandleFunc(&config.File{Path: subPath, Operation: config.Create}, handler)
+
+	//Neither removal forgets the files that the others still see
+	listener.Remove(file)
+	listener.Remove(dir)
+
+	go listener.Run(time.Millisecond * 100)
+	defer listener.Stop(context.Background())
+
+	select {
+	case path := <-events:
+		t.Errorf("Unexpected event for %s", path)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestWithin(t *testing.T) {
+	assert.True(t, within("/a", "/a"))
+	assert.True(t, within("/a", "/a/b/c"))
+	assert.False(t, within("/a/b", "/a"))
+	assert.False(t, within("/a", "/ab"))
+	assert.False(t, within("/a", "/b/..a"))
+}
+
+func TestRemoveWhileHandlerBlocks(t *testing.T) {
+	basePath := setup()
+
+	listener := NewFile(logrus.New())
+
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	condition := &config.File{
+		Path:      basePath,
+		Operation: config.Create,
+	}
+
+	id, _ := listener.HandleFunc(condition, func(event.Event) {
+		blocked <- struct{}{}
+		<-release
+	})
+
+	go listener.Run(time.Millisecond * 100)
+
+	createDummyFile(basePath)
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	case <-blocked:
+	}
+
+	removed := make(chan struct{})
+	go func() {
+		listener.Remove(id)
+		close(removed)
+	}()
+
+	select {
+	case <-time.After(500 * time.Millisecond):
+		t.Error("Remove blocked on a running handler")
+	case <-removed:
+	}
+}
+
 func TestStopNotRunning(t *testing.T) {
 	file := NewFile(logrus.New())
 
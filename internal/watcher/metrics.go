@@ -0,0 +1,27 @@
+package watcher
+
+import (
+	"github.com/mickyco94/saucisson/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	fileEvents = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "saucisson_file_events_total",
+		Help: "Number of file system events observed by the file watcher, by operation",
+	}, []string{"op"})
+	fileErrors = metrics.Factory.NewCounter(prometheus.CounterOpts{
+		Name: "saucisson_file_errors_total",
+		Help: "Number of errors reported by the file watcher",
+	})
+
+	processPollDuration = metrics.Factory.NewHistogram(prometheus.HistogramOpts{
+		Name:    "saucisson_process_poll_duration_seconds",
+		Help:    "Time taken by the process watcher to list the running processes",
+		Buckets: metrics.DefaultBuckets,
+	})
+	processErrors = metrics.Factory.NewCounter(prometheus.CounterOpts{
+		Name: "saucisson_process_errors_total",
+		Help: "Number of times the process watcher failed to list the running processes",
+	})
+)
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
 	"github.com/mitchellh/go-ps"
 	"github.com/sirupsen/logrus"
 )
@@ -21,7 +22,9 @@ const (
 type Processes func() ([]ps.Process, error)
 
 type Process struct {
-	source Processes
+	//source is guarded by sourceMu, as tests replace it while polling
+	sourceMu sync.Mutex
+	source   Processes
 
 	logger logrus.FieldLogger
 
@@ -30,15 +33,23 @@ type Process struct {
 	close     chan struct{}
 	running   bool
 
-	entries  []processEntry
-	watching map[string]struct{}
+	entriesMu sync.Mutex
+	entries   []processEntry
+	watching  map[string]struct{}
 }
 
 type processEntry struct {
+	id         ID
 	executable string
 	listenFor  State
 	isRunning  bool
-	h          func()
+	//primed is false until the initial state of the process has been observed,
+	//this prevents entries added while running from firing immediately
+	primed bool
+	//last is the most recently observed process for the executable,
+	//used to describe the process once it has closed
+	last ps.Process
+	h    func(event.Event)
 }
 
 func NewProcess(logger logrus.FieldLogger) *Process {
@@ -59,32 +70,82 @@ var stateStringToEnum = map[config.State]State{
 	config.Open:  Open,
 }
 
-func (p *Process) HandleFunc(config *config.Process, f func()) {
+var stateEnumToString = map[State]config.State{
+	Close: config.Close,
+	Open:  config.Open,
+}
+
+// HandleFunc registers the provided function to be executed when the
+// process changes to the state specified by the condition
+func (p *Process) HandleFunc(config *config.Process, f func(event.Event)) ID {
+	p.entriesMu.Lock()
+	defer p.entriesMu.Unlock()
 
 	entry := processEntry{
+		id:         nextID(),
 		executable: config.Executable,
 		listenFor:  stateStringToEnum[config.State],
 		isRunning:  false,
+		primed:     false,
 		h:          f,
 	}
 
 	p.entries = append(p.entries, entry)
 	p.watching[config.Executable] = struct{}{}
+
+	return entry.id
 }
 
-func (entry processEntry) startJob() {
-	go entry.h()
+// Remove deregisters the handler with the provided id.
+// Removing an unknown id is a no-op
+func (p *Process) Remove(id ID) {
+	p.entriesMu.Lock()
+	defer p.entriesMu.Unlock()
+
+	for i, entry := range p.entries {
+		if entry.id == id {
+			p.entries = append(p.entries[:i], p.entries[i+1:]...)
+			break
+		}
+	}
+
+	p.watching = make(map[string]struct{}, len(p.entries))
+	for _, entry := range p.entries {
+		p.watching[entry.executable] = struct{}{}
+	}
+}
+
+func (entry processEntry) startJob(process ps.Process) {
+	ev := event.New(config.Processkey)
+	ev.Executable = entry.executable
+	ev.State = stateEnumToString[entry.listenFor]
+
+	if process != nil {
+		ev.PID = process.Pid()
+		ev.PPID = process.PPid()
+	}
+
+	go entry.h(ev)
 }
 
 func (p *Process) processes() ([]ps.Process, error) {
 	backoff := 1
 
+	p.sourceMu.Lock()
+	source := p.source
+	p.sourceMu.Unlock()
+
 	for {
-		procs, err := p.source()
+		started := time.Now()
+		procs, err := source()
+		processPollDuration.Observe(time.Since(started).Seconds())
+
 		if err == nil {
 			return procs, nil
 		}
 
+		processErrors.Inc()
+
 		if backoff > 32 {
 			return nil, err
 		}
@@ -102,7 +163,7 @@ func (p *Process) processes() ([]ps.Process, error) {
 var pollingInterval = 100 * time.Millisecond
 
 func (p *Process) setInitialState() error {
-	if len(p.entries) == 0 {
+	if p.empty() {
 		//No state to set
 		return nil
 	}
@@ -113,25 +174,48 @@ func (p *Process) setInitialState() error {
 		return err
 	}
 
-	runningProcs := make(map[string]struct{})
+	p.entriesMu.Lock()
+	defer p.entriesMu.Unlock()
 
-	for _, process := range processes {
-		_, watching := p.watching[process.Executable()]
-		//perf: Micro-optimisation to make runningProcs small as possible
-		if watching {
-			runningProcs[process.Executable()] = struct{}{}
-		}
-	}
+	runningProcs := p.runningProcs(processes)
 
 	for i, entry := range p.entries {
-		_, isRunning := runningProcs[entry.executable]
+		process, isRunning := runningProcs[entry.executable]
 
 		p.entries[i].isRunning = isRunning
+		p.entries[i].primed = true
+		if isRunning {
+			p.entries[i].last = process
+		}
 	}
 
 	return nil
 }
 
+// empty reports whether there are no registered entries
+func (p *Process) empty() bool {
+	p.entriesMu.Lock()
+	defer p.entriesMu.Unlock()
+
+	return len(p.entries) == 0
+}
+
+// runningProcs filters processes down to the set of watched executables
+// that are running. Must be called with entriesMu held
+func (p *Process) runningProcs(processes []ps.Process) map[string]ps.Process {
+	runningProcs := make(map[string]ps.Process)
+
+	for _, process := range processes {
+		_, watching := p.watching[process.Executable()]
+		//perf: Micro-optimisation to make runningProcs small as possible
+		if watching {
+			runningProcs[process.Executable()] = process
+		}
+	}
+
+	return runningProcs
+}
+
 func (p *Process) Run() error {
 	p.runningMu.Lock()
 	if p.running {
@@ -158,7 +242,7 @@ func (p *Process) run() error {
 			p.done <- struct{}{}
 			return nil
 		case <-time.After(pollingInterval):
-			if len(p.entries) == 0 {
+			if p.empty() {
 				continue
 			}
 
@@ -168,30 +252,40 @@ func (p *Process) run() error {
 				return err
 			}
 
-			runningProcs := make(map[string]struct{})
+			p.poll(processes)
+		}
+	}
+}
 
-			for _, process := range processes {
-				_, watching := p.watching[process.Executable()]
-				//perf: Micro-optimisation to make runningProcs small as possible
-				if watching {
-					runningProcs[process.Executable()] = struct{}{}
-				}
-			}
+// poll compares the running processes against the last observed state
+// of each entry, starting the jobs of any entries whose state has changed
+func (p *Process) poll(processes []ps.Process) {
+	p.entriesMu.Lock()
+	defer p.entriesMu.Unlock()
+
+	runningProcs := p.runningProcs(processes)
 
-			for i, entry := range p.entries {
-				_, isRunning := runningProcs[entry.executable]
+	for i, entry := range p.entries {
+		process, isRunning := runningProcs[entry.executable]
+		if isRunning {
+			p.entries[i].last = process
+		}
 
-				if isRunning && entry.listenFor == Open && !entry.isRunning {
-					entry.startJob()
-				}
+		if !entry.primed {
+			p.entries[i].isRunning = isRunning
+			p.entries[i].primed = true
+			continue
+		}
 
-				if !isRunning && entry.listenFor == Close && entry.isRunning {
-					entry.startJob()
-				}
+		if isRunning && entry.listenFor == Open && !entry.isRunning {
+			entry.startJob(process)
+		}
 
-				p.entries[i].isRunning = isRunning
-			}
+		if !isRunning && entry.listenFor == Close && entry.isRunning {
+			entry.startJob(entry.last)
 		}
+
+		p.entries[i].isRunning = isRunning
 	}
 }
 
@@ -200,8 +294,11 @@ func (p *Process) run() error {
 // it has successfully closed.
 // If `Process` is already stopped then this noops
 func (proc *Process) Stop(ctx context.Context) error {
+	proc.runningMu.Lock()
+	running := proc.running
+	proc.runningMu.Unlock()
 
-	if !proc.running {
+	if !running {
 		return nil
 	}
 
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
 	"github.com/mitchellh/go-ps"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -29,6 +30,8 @@ func (m mockProcess) Pid() int {
 }
 
 func (p *Process) setRunning(process string, isRunning bool) {
+	p.sourceMu.Lock()
+	defer p.sourceMu.Unlock()
 
 	if isRunning {
 		p.source = func() ([]ps.Process, error) {
@@ -43,15 +46,15 @@ func (p *Process) setRunning(process string, isRunning bool) {
 
 func TestListenForOpen(t *testing.T) {
 
-	called := make(chan struct{})
+	called := make(chan event.Event)
 
 	proc := NewProcess(logrus.New())
 
 	proc.HandleFunc(&config.Process{
 		Executable: "top",
 		State:      config.Open,
-	}, func() {
-		called <- struct{}{}
+	}, func(ev event.Event) {
+		called <- ev
 	})
 
 	go proc.Run()
@@ -63,8 +66,11 @@ func TestListenForOpen(t *testing.T) {
 	select {
 	case <-time.After(1 * time.Second):
 		t.Fail()
-	case <-called:
-		t.Log("Passed")
+	case ev := <-called:
+		assert.Equal(t, "top", ev.Executable)
+		assert.Equal(t, config.Open, ev.State)
+		assert.Equal(t, 2, ev.PID)
+		assert.Equal(t, 1, ev.PPID)
 	}
 
 	proc.Stop(context.Background())
@@ -79,7 +85,7 @@ func TestCloseWhenAlreadyRunning(t *testing.T) {
 	proc.HandleFunc(&config.Process{
 		Executable: "top",
 		State:      config.Close,
-	}, func() {
+	}, func(event.Event) {
 		called <- struct{}{}
 	})
 
@@ -110,7 +116,7 @@ func TestOpenWhenAlreadyOpen(t *testing.T) {
 	proc.HandleFunc(&config.Process{
 		Executable: "top",
 		State:      config.Open,
-	}, func() {
+	}, func(event.Event) {
 		called <- struct{}{}
 	})
 
@@ -134,14 +140,14 @@ func TestOpenAndClose(t *testing.T) {
 	proc.HandleFunc(&config.Process{
 		Executable: "top",
 		State:      config.Open,
-	}, func() {
+	}, func(event.Event) {
 		opened <- struct{}{}
 	})
 
 	proc.HandleFunc(&config.Process{
 		Executable: "top",
 		State:      config.Close,
-	}, func() {
+	}, func(event.Event) {
 		closed <- struct{}{}
 	})
 
@@ -194,3 +200,68 @@ func TestNoEntries(t *testing.T) {
 	case <-time.After(500 * time.Millisecond):
 	}
 }
+
+func TestRemove(t *testing.T) {
+	called := make(chan struct{}, 1)
+
+	proc := NewProcess(logrus.New())
+
+	id := proc.HandleFunc(&config.Process{
+		Executable: "top",
+		State:      config.Open,
+	}, func(event.Event) {
+		called <- struct{}{}
+	})
+
+	go proc.Run()
+
+	<-time.After(500 * time.Millisecond)
+
+	proc.Remove(id)
+	proc.setRunning("top", true)
+
+	select {
+	case <-called:
+		t.Fail()
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	proc.Stop(context.Background())
+}
+
+func TestHandleFuncWhileRunning(t *testing.T) {
+	called := make(chan struct{}, 1)
+
+	proc := NewProcess(logrus.New())
+	proc.setRunning("top", true)
+
+	go proc.Run()
+
+	<-time.After(200 * time.Millisecond)
+
+	//top is already open so this should not fire until it is reopened
+	proc.HandleFunc(&config.Process{
+		Executable: "top",
+		State:      config.Open,
+	}, func(event.Event) {
+		called <- struct{}{}
+	})
+
+	select {
+	case <-called:
+		t.Fail()
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	proc.setRunning("top", false)
+	<-time.After(200 * time.Millisecond)
+	proc.setRunning("top", true)
+
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fail()
+	}
+
+	proc.Stop(context.Background())
+}
@@ -0,0 +1,301 @@
+package watcher
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/mickyco94/saucisson/internal/executor"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxBatchSize is the most events kept in a batch, older events are dropped
+// from the batch once it is full
+const MaxBatchSize = 1000
+
+// DefaultMaxWaitFactor is the multiple of the delay that a Debounce waits at
+// most, unless a maximum is given
+const DefaultMaxWaitFactor = 10
+
+// Debounce invokes its handler once events have stopped arriving for the delay,
+// or maxWait after the first event of the batch, so that a steady stream of
+// events cannot hold the handler back forever.
+// The handler receives the last event, with every event since the handler
+// was last invoked as its Batch, up to MaxBatchSize
+type Debounce struct {
+	logger   logrus.FieldLogger
+	mu       sync.Mutex
+	delay    time.Duration
+	maxWait  time.Duration
+	batch    []event.Event
+	timer    *time.Timer
+	maxTimer *time.Timer
+	stopped  bool
+	handler  func(event.Event) error
+}
+
+// NewDebounce constructs a Debounce that waits for delay, and at most maxWait.
+// If maxWait is not positive it is DefaultMaxWaitFactor times delay
+func NewDebounce(logger logrus.FieldLogger, delay, maxWait time.Duration, handler func(event.Event) error) *Debounce {
+	if maxWait <= 0 {
+		maxWait = DefaultMaxWaitFactor * delay
+	}
+
+	return &Debounce{
+		logger:  logger,
+		delay:   delay,
+		maxWait: maxWait,
+		handler: handler,
+	}
+}
+
+// Handle adds ev to the batch and restarts the delay.
+// The handler is invoked later, so its error is logged rather than returned,
+// and ev is reported as not run for now
+func (debounce *Debounce) Handle(ev event.Event) error {
+	debounce.mu.Lock()
+	defer debounce.mu.Unlock()
+
+	notRun := &executor.NotRunError{Reason: "debounced"}
+
+	if debounce.stopped {
+		return notRun
+	}
+
+	debounce.batch = appendBatch(debounce.batch, ev)
+	if len(debounce.batch) > MaxBatchSize {
+		debounce.batch = append([]event.Event(nil), debounce.batch[len(debounce.batch)-MaxBatchSize:]...)
+	}
+
+	if debounce.timer != nil {
+		debounce.timer.Stop()
+	}
+	debounce.timer = time.AfterFunc(debounce.delay, debounce.flush)
+
+	if debounce.maxTimer == nil {
+		debounce.maxTimer = time.AfterFunc(debounce.maxWait, debounce.flush)
+	}
+
+	return notRun
+}
+
+// flush invokes the handler with the batch, if it has not already been
+func (debounce *Debounce) flush() {
+	debounce.mu.Lock()
+	batch := debounce.batch
+	debounce.batch = nil
+	debounce.stopTimers()
+	debounce.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	ev := batched(batch)
+	err := debounce.handler(ev)
+	if rejected(err) {
+		debounce.logger.
+			WithError(err).
+			WithField("batch_size", len(ev.Batch)).
+			Warn("Debounced event was rejected")
+	}
+}
+
+// stopTimers stops the delay and maxWait, debounce.mu must be held
+func (debounce *Debounce) stopTimers() {
+	if debounce.timer != nil {
+		debounce.timer.Stop()
+		debounce.timer = nil
+	}
+	if debounce.maxTimer != nil {
+		debounce.maxTimer.Stop()
+		debounce.maxTimer = nil
+	}
+}
+
+// Stop discards the batch, the handler is not invoked again
+func (debounce *Debounce) Stop() {
+	debounce.mu.Lock()
+	defer debounce.mu.Unlock()
+
+	debounce.stopped = true
+	debounce.batch = nil
+	debounce.stopTimers()
+}
+
+// Throttle invokes its handler at most once per interval.
+// The first event is passed on straight away. Events that arrive within
+// the interval are held back, and the last of them is passed on once the
+// interval has passed, so that the final event of a burst is not lost
+type Throttle struct {
+	logger   logrus.FieldLogger
+	mu       sync.Mutex
+	interval time.Duration
+	last     time.Time
+	pending  *event.Event
+	timer    *time.Timer
+	stopped  bool
+	handler  func(event.Event) error
+	now      func() time.Time
+}
+
+// NewThrottle constructs a Throttle that passes on one event per interval
+func NewThrottle(logger logrus.FieldLogger, interval time.Duration, handler func(event.Event) error) *Throttle {
+	return &Throttle{
+		logger:   logger,
+		interval: interval,
+		handler:  handler,
+		now:      time.Now, //Setting this here supports mocking
+	}
+}
+
+// Handle passes ev on to the handler, along with any error, unless an
+// event was passed on within the interval. In that case ev is held back
+// until the interval has passed, replacing any event already held back,
+// and is reported as not run for now
+func (throttle *Throttle) Handle(ev event.Event) error {
+	throttle.mu.Lock()
+
+	notRun := &executor.NotRunError{Reason: "throttled"}
+
+	if throttle.stopped {
+		throttle.mu.Unlock()
+		return notRun
+	}
+
+	now := throttle.now()
+	if elapsed := now.Sub(throttle.last); !throttle.last.IsZero() && elapsed < throttle.interval {
+		throttle.pending = &ev
+		if throttle.timer == nil {
+			throttle.timer = time.AfterFunc(throttle.interval-elapsed, throttle.trailing)
+		}
+		throttle.mu.Unlock()
+		return notRun
+	}
+	throttle.last = now
+	//ev is newer than any event held back
+	throttle.pending = nil
+
+	throttle.mu.Unlock()
+
+	return throttle.handler(ev)
+}
+
+// trailing passes on the event held back, if there is one
+func (throttle *Throttle) trailing() {
+	throttle.mu.Lock()
+	throttle.timer = nil
+	pending := throttle.pending
+	throttle.pending = nil
+	if pending == nil || throttle.stopped {
+		throttle.mu.Unlock()
+		return
+	}
+	throttle.last = throttle.now()
+	throttle.mu.Unlock()
+
+	err := throttle.handler(*pending)
+	if rejected(err) {
+		throttle.logger.
+			WithError(err).
+			Warn("Throttled event was rejected")
+	}
+}
+
+// Stop discards the event held back, the handler is not invoked again
+func (throttle *Throttle) Stop() {
+	throttle.mu.Lock()
+	defer throttle.mu.Unlock()
+
+	throttle.stopped = true
+	throttle.pending = nil
+	if throttle.timer != nil {
+		throttle.timer.Stop()
+		throttle.timer = nil
+	}
+}
+
+// Threshold invokes its handler once count events have arrived within the window.
+// The handler receives the last event, with the events that reached the threshold
+// as its Batch. Counting restarts once the handler has been invoked
+type Threshold struct {
+	mu      sync.Mutex
+	count   int
+	window  time.Duration
+	batch   []event.Event
+	arrived []time.Time
+	handler func(event.Event) error
+	now     func() time.Time
+}
+
+// NewThreshold constructs a Threshold of count events within window
+func NewThreshold(count int, window time.Duration, handler func(event.Event) error) *Threshold {
+	return &Threshold{
+		count:   count,
+		window:  window,
+		handler: handler,
+		now:     time.Now, //Setting this here supports mocking
+	}
+}
+
+// Handle counts ev, passing the batch on to the handler, along with
+// any error, if the threshold has been reached. Otherwise ev is reported
+// as not run
+func (threshold *Threshold) Handle(ev event.Event) error {
+	threshold.mu.Lock()
+
+	now := threshold.now()
+
+	//Events that arrived before the window are no longer counted
+	expired := 0
+	for expired < len(threshold.arrived) && now.Sub(threshold.arrived[expired]) > threshold.window {
+		expired++
+	}
+	threshold.arrived = threshold.arrived[expired:]
+	threshold.batch = threshold.batch[expired:]
+
+	threshold.arrived = append(threshold.arrived, now)
+	threshold.batch = append(threshold.batch, ev)
+
+	if len(threshold.batch) < threshold.count {
+		threshold.mu.Unlock()
+		return &executor.NotRunError{Reason: "below threshold"}
+	}
+
+	batch := threshold.batch
+	threshold.batch = nil
+	threshold.arrived = nil
+
+	threshold.mu.Unlock()
+
+	return threshold.handler(batched(batch))
+}
+
+// rejected reports whether err is an error of the handler, rather than the
+// event being intentionally not run, which needs no warning
+func rejected(err error) bool {
+	var notRun *executor.NotRunError
+	return err != nil && !errors.As(err, &notRun)
+}
+
+// appendBatch adds ev to batch, or the events of its batch if it
+// has already been combined
+func appendBatch(batch []event.Event, ev event.Event) []event.Event {
+	if len(ev.Batch) > 0 {
+		return append(batch, ev.Batch...)
+	}
+	return append(batch, ev)
+}
+
+// batched combines the events of batch into a copy of the last
+func batched(batch []event.Event) event.Event {
+	combined := make([]event.Event, 0, len(batch))
+	for _, ev := range batch {
+		combined = appendBatch(combined, ev)
+	}
+
+	ev := batch[len(batch)-1]
+	ev.Batch = combined
+	return ev
+}
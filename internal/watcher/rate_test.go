@@ -0,0 +1,218 @@
+package watcher
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/mickyco94/saucisson/internal/executor"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebounceFiresOnceWithBatch(t *testing.T) {
+	mu := sync.Mutex{}
+	received := []event.Event{}
+
+	debounce := NewDebounce(logrus.New(), 20*time.Millisecond, 0, func(ev event.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, ev)
+		return nil
+	})
+
+	var notRun *executor.NotRunError
+	assert.ErrorAs(t, debounce.Handle(event.Event{Path: "a"}), &notRun)
+	debounce.Handle(event.Event{Path: "b"})
+	debounce.Handle(event.Event{Path: "c"})
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 1
+	}, time.Second, time.Millisecond)
+
+	//Nothing else is waiting to fire
+	time.Sleep(40 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Len(t, received, 1)
+	assert.Equal(t, "c", received[0].Path)
+	assert.Equal(t, []event.Event{{Path: "a"}, {Path: "b"}, {Path: "c"}}, received[0].Batch)
+}
+
+func TestDebounceStop(t *testing.T) {
+	fired := make(chan event.Event, 1)
+
+	debounce := NewDebounce(logrus.New(), 10*time.Millisecond, 0, func(ev event.Event) error {
+		fired <- ev
+		return nil
+	})
+
+	debounce.Handle(event.Event{})
+	debounce.Stop()
+	debounce.Handle(event.Event{})
+
+	select {
+	case <-fired:
+		t.Fatal("handler invoked after stop")
+	case <-time.After(40 * time.Millisecond):
+	}
+}
+
+func TestDebounceMaxWait(t *testing.T) {
+	fired := make(chan event.Event, 1)
+
+	debounce := NewDebounce(logrus.New(), 20*time.Millisecond, 50*time.Millisecond, func(ev event.Event) error {
+		fired <- ev
+		return nil
+	})
+	defer debounce.Stop()
+
+	//A steady stream of events keeps restarting the delay
+	started := time.Now()
+	for time.Since(started) < 200*time.Millisecond {
+		debounce.Handle(event.Event{})
+
+		select {
+		case ev := <-fired:
+			assert.NotEmpty(t, ev.Batch)
+			return
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	t.Fatal("handler not invoked within max_wait")
+}
+
+func TestDebounceBatchSize(t *testing.T) {
+	fired := make(chan event.Event, 1)
+
+	debounce := NewDebounce(logrus.New(), 10*time.Millisecond, 0, func(ev event.Event) error {
+		fired <- ev
+		return nil
+	})
+
+	for i := 0; i < MaxBatchSize+10; i++ {
+		debounce.Handle(event.Event{PID: i})
+	}
+
+	ev := <-fired
+	assert.Len(t, ev.Batch, MaxBatchSize)
+	assert.Equal(t, MaxBatchSize+9, ev.PID)
+	assert.Equal(t, 10, ev.Batch[0].PID)
+}
+
+func TestThrottle(t *testing.T) {
+	fired := 0
+	c := &clock{current: time.Now()}
+
+	throttle := NewThrottle(logrus.New(), time.Minute, func(event.Event) error { fired++; return nil })
+	throttle.now = c.now
+
+	var notRun *executor.NotRunError
+	assert.NoError(t, throttle.Handle(event.Event{}))
+	assert.ErrorAs(t, throttle.Handle(event.Event{}), &notRun)
+	assert.Equal(t, 1, fired)
+
+	c.advance(30 * time.Second)
+	throttle.Handle(event.Event{})
+	assert.Equal(t, 1, fired)
+
+	c.advance(31 * time.Second)
+	throttle.Handle(event.Event{})
+	assert.Equal(t, 2, fired)
+
+	throttle.Stop()
+}
+
+func TestThrottleTrailing(t *testing.T) {
+	fired := make(chan event.Event, 3)
+
+	throttle := NewThrottle(logrus.New(), 20*time.Millisecond, func(ev event.Event) error {
+		fired <- ev
+		return nil
+	})
+
+	throttle.Handle(event.Event{Path: "a"})
+	throttle.Handle(event.Event{Path: "b"})
+	throttle.Handle(event.Event{Path: "c"})
+
+	assert.Equal(t, "a", (<-fired).Path)
+
+	//The last event of the burst is passed on once the interval has passed
+	select {
+	case ev := <-fired:
+		assert.Equal(t, "c", ev.Path)
+	case <-time.After(time.Second):
+		t.Fatal("trailing event was not passed on")
+	}
+
+	select {
+	case ev := <-fired:
+		t.Fatalf("unexpected event %q", ev.Path)
+	case <-time.After(40 * time.Millisecond):
+	}
+}
+
+func TestThrottleStop(t *testing.T) {
+	fired := make(chan event.Event, 2)
+
+	throttle := NewThrottle(logrus.New(), 10*time.Millisecond, func(ev event.Event) error {
+		fired <- ev
+		return nil
+	})
+
+	throttle.Handle(event.Event{Path: "a"})
+	throttle.Handle(event.Event{Path: "b"})
+	throttle.Stop()
+	<-fired
+
+	select {
+	case <-fired:
+		t.Fatal("handler invoked after stop")
+	case <-time.After(40 * time.Millisecond):
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	received := []event.Event{}
+	c := &clock{current: time.Now()}
+
+	threshold := NewThreshold(3, time.Minute, func(ev event.Event) error {
+		received = append(received, ev)
+		return nil
+	})
+	threshold.now = c.now
+
+	threshold.Handle(event.Event{Path: "a"})
+	c.advance(2 * time.Minute)
+
+	//The first event is outside the window, so is no longer counted
+	var notRun *executor.NotRunError
+	threshold.Handle(event.Event{Path: "b"})
+	assert.ErrorAs(t, threshold.Handle(event.Event{Path: "c"}), &notRun)
+	assert.Empty(t, received)
+
+	assert.NoError(t, threshold.Handle(event.Event{Path: "d"}))
+	assert.Len(t, received, 1)
+	assert.Equal(t, "d", received[0].Path)
+	assert.Equal(t, []event.Event{{Path: "b"}, {Path: "c"}, {Path: "d"}}, received[0].Batch)
+
+	//Counting restarts
+	threshold.Handle(event.Event{Path: "e"})
+	assert.Len(t, received, 1)
+}
+
+func TestBatchedFlattens(t *testing.T) {
+	ev := batched([]event.Event{
+		{Path: "a", Batch: []event.Event{{Path: "x"}, {Path: "a"}}},
+		{Path: "b"},
+	})
+
+	assert.Equal(t, "b", ev.Path)
+	assert.Equal(t, []event.Event{{Path: "x"}, {Path: "a"}, {Path: "b"}}, ev.Batch)
+}
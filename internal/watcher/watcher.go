@@ -0,0 +1,15 @@
+package watcher
+
+import "sync/atomic"
+
+// ID identifies a handler registered with a watcher, it is returned by
+// HandleFunc so that the handler can later be deregistered with Remove.
+// IDs are unique across all watchers.
+type ID uint64
+
+var lastID uint64
+
+// nextID allocates a new unique ID
+func nextID() ID {
+	return ID(atomic.AddUint64(&lastID, 1))
+}
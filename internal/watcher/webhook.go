@@ -0,0 +1,320 @@
+package watcher
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/mickyco94/saucisson/internal/executor"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxWebhookBodySize is the largest request body accepted by the webhook server
+var MaxWebhookBodySize int64 = 10 << 20
+
+// Default headers that carry the secret, or signature, of a webhook request
+const (
+	DefaultTokenHeader     = "X-Saucisson-Token"
+	DefaultSignatureHeader = "X-Hub-Signature-256"
+)
+
+// Timeouts of the webhook server, so that slow or idle clients cannot hold
+// connections open indefinitely
+const (
+	webhookReadHeaderTimeout = 10 * time.Second
+	webhookReadTimeout       = time.Minute
+	webhookIdleTimeout       = 2 * time.Minute
+)
+
+type webhookEntry struct {
+	id      ID
+	path    string
+	methods []string
+	secret  string
+	verify  config.Verification
+	header  string
+	//handler returns an error if the event could not be accepted
+	handler func(event.Event) error
+}
+
+// Webhook is a HTTP server that satisfies webhook conditions when it receives
+// requests for their paths.
+//
+// Requests are answered with:
+//   - 202 once the handler has accepted the event
+//   - 200 with the reasons if the event was intentionally not run, see
+//     executor.NotRunError, e.g. because the service is paused
+//   - 429 if the handler returned executor.ErrQueueFull
+//   - 500 if the handler returned any other error
+//   - 401 if the request could not be verified
+//   - 404 or 405 if no condition matches the path or method
+type Webhook struct {
+	logger logrus.FieldLogger
+
+	runningMu sync.Mutex
+	server    *http.Server
+
+	entriesMu sync.RWMutex
+	entries   []webhookEntry
+}
+
+// NewWebhook constructs a webhook server, it does not listen until Listen is called
+func NewWebhook(logger logrus.FieldLogger) *Webhook {
+	return &Webhook{
+		logger:  logger,
+		entries: make([]webhookEntry, 0),
+	}
+}
+
+// HandleFunc registers the provided function to be executed when a request
+// for the path of the condition is received. The error returned by handler
+// decides the status of the response, see Webhook
+func (w *Webhook) HandleFunc(condition *config.Webhook, handler func(event.Event) error) ID {
+	entry := webhookEntry{
+		id:      nextID(),
+		path:    condition.Path,
+		methods: condition.Methods,
+		secret:  condition.Secret,
+		verify:  condition.Verify,
+		header:  condition.Header,
+		handler: handler,
+	}
+
+	if len(entry.methods) == 0 {
+		entry.methods = []string{http.MethodPost}
+	}
+
+	if entry.header == "" {
+		switch entry.verify {
+		case config.Token:
+			entry.header = DefaultTokenHeader
+		case config.HmacSha256:
+			entry.header = DefaultSignatureHeader
+		}
+	}
+
+	w.entriesMu.Lock()
+	defer w.entriesMu.Unlock()
+
+	w.entries = append(w.entries, entry)
+
+	return entry.id
+}
+
+// Remove deregisters the handler with the provided id.
+// Removing an unknown id is a no-op
+func (w *Webhook) Remove(id ID) {
+	w.entriesMu.Lock()
+	defer w.entriesMu.Unlock()
+
+	for i, entry := range w.entries {
+		if entry.id == id {
+			w.entries = append(w.entries[:i], w.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// Listen binds to the provided address and serves requests on a
+// separate goroutine. If the server is already listening then it is
+// stopped once the new address is bound, this allows the address to be
+// changed without interruption. If binding fails the server is unchanged
+func (w *Webhook) Listen(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	err = w.Stop(context.Background())
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           w,
+		ReadHeaderTimeout: webhookReadHeaderTimeout,
+		ReadTimeout:       webhookReadTimeout,
+		IdleTimeout:       webhookIdleTimeout,
+	}
+
+	w.runningMu.Lock()
+	w.server = server
+	w.runningMu.Unlock()
+
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			w.logger.
+				WithError(err).
+				WithField("address", address).
+				Error("Webhook server failed")
+		}
+	}()
+
+	w.logger.
+		WithField("address", listener.Addr().String()).
+		Debug("Webhook server listening")
+
+	return nil
+}
+
+// Stop gracefully shuts down the server, waiting for in-flight requests
+// until the context is done. If the server is not listening this noops
+func (w *Webhook) Stop(ctx context.Context) error {
+	w.runningMu.Lock()
+	server := w.server
+	w.server = nil
+	w.runningMu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	return server.Shutdown(ctx)
+}
+
+// ServeHTTP dispatches the request to every condition registered for its path
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.entriesMu.RLock()
+	matched := make([]webhookEntry, 0)
+	allowed := make([]string, 0)
+	pathExists := false
+
+	for _, entry := range w.entries {
+		if entry.path != r.URL.Path {
+			continue
+		}
+		pathExists = true
+		allowed = append(allowed, entry.methods...)
+		if contains(entry.methods, r.Method) {
+			matched = append(matched, entry)
+		}
+	}
+	w.entriesMu.RUnlock()
+
+	if !pathExists {
+		http.NotFound(rw, r)
+		return
+	}
+
+	if len(matched) == 0 {
+		rw.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, MaxWebhookBodySize))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	ev := event.New(config.WebhookKey)
+	ev.Path = r.URL.Path
+	ev.Method = r.Method
+	ev.Headers = r.Header.Clone()
+	ev.Query = r.URL.Query()
+	ev.Body = string(body)
+
+	//The secret, or signature, is not passed on to executors and history
+	for _, entry := range matched {
+		if entry.header != "" {
+			ev.Headers.Del(entry.header)
+		}
+	}
+
+	verified, accepted := 0, 0
+	var saturated, failed error
+	reasons := make([]string, 0)
+
+	for _, entry := range matched {
+		if !entry.verified(r.Header, body) {
+			continue
+		}
+		verified++
+
+		err := entry.handler(ev)
+		var notRun *executor.NotRunError
+		switch {
+		case err == nil:
+			accepted++
+		case errors.As(err, &notRun):
+			reasons = append(reasons, notRun.Reason)
+		case errors.Is(err, executor.ErrQueueFull):
+			saturated = err
+		default:
+			failed = err
+		}
+	}
+
+	logger := w.logger.
+		WithField("path", r.URL.Path).
+		WithField("method", r.Method).
+		WithField("remote", r.RemoteAddr)
+
+	switch {
+	case verified == 0:
+		logger.Warn("Webhook request failed verification")
+		http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	case failed != nil:
+		logger.WithError(failed).Error("Webhook request failed")
+		http.Error(rw, failed.Error(), http.StatusInternalServerError)
+	case saturated != nil:
+		logger.WithError(saturated).Warn("Webhook request rejected")
+		http.Error(rw, saturated.Error(), http.StatusTooManyRequests)
+	case accepted > 0:
+		rw.WriteHeader(http.StatusAccepted)
+	default:
+		logger.WithField("reasons", reasons).Debug("Webhook event was not run")
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		rw.WriteHeader(http.StatusOK)
+		io.WriteString(rw, "Not run: "+strings.Join(reasons, ", ")+"\n")
+	}
+}
+
+// verified reports whether the request carries the secret of the entry,
+// entries without verification accept every request
+func (entry webhookEntry) verified(header http.Header, body []byte) bool {
+	provided := header.Get(entry.header)
+
+	switch entry.verify {
+	case config.Token:
+		return subtle.ConstantTimeCompare([]byte(provided), []byte(entry.secret)) == 1
+	case config.HmacSha256:
+		if !strings.HasPrefix(provided, "sha256=") {
+			return false
+		}
+
+		decoded, err := hex.DecodeString(strings.TrimPrefix(provided, "sha256="))
+		if err != nil {
+			return false
+		}
+
+		mac := hmac.New(sha256.New, []byte(entry.secret))
+		mac.Write(body)
+		return hmac.Equal(decoded, mac.Sum(nil))
+	default:
+		return true
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package watcher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mickyco94/saucisson/internal/config"
+	"github.com/mickyco94/saucisson/internal/event"
+	"github.com/mickyco94/saucisson/internal/executor"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(w *Webhook, method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range headers {
+		request.Header.Set(k, v)
+	}
+
+	recorder := httptest.NewRecorder()
+	w.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestWebhookAccepted(t *testing.T) {
+	w := NewWebhook(logrus.New())
+
+	received := make(chan event.Event, 1)
+	w.HandleFunc(&config.Webhook{Path: "/deploy"}, func(ev event.Event) error {
+		received <- ev
+		return nil
+	})
+
+	response := serve(w, http.MethodPost, "/deploy?ref=main", "payload", nil)
+
+	assert.Equal(t, http.StatusAccepted, response.Code)
+
+	ev := <-received
+	assert.Equal(t, config.WebhookKey, ev.Condition)
+	assert.Equal(t, "/deploy", ev.Path)
+	assert.Equal(t, "main", ev.Query.Get("ref"))
+	assert.Equal(t, "payload", ev.Body)
+}
+
+func TestWebhookRouting(t *testing.T) {
+	w := NewWebhook(logrus.New())
+
+	w.HandleFunc(&config.Webhook{Path: "/deploy", Methods: []string{http.MethodPut}}, func(ev event.Event) error {
+		return nil
+	})
+
+	assert.Equal(t, http.StatusNotFound, serve(w, http.MethodPut, "/other", "", nil).Code)
+
+	response := serve(w, http.MethodPost, "/deploy", "", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+	assert.Equal(t, http.MethodPut, response.Header().Get("Allow"))
+}
+
+func TestWebhookSaturated(t *testing.T) {
+	w := NewWebhook(logrus.New())
+
+	w.HandleFunc(&config.Webhook{Path: "/deploy"}, func(ev event.Event) error {
+		return fmt.Errorf("enqueue: %w", executor.ErrQueueFull)
+	})
+
+	assert.Equal(t, http.StatusTooManyRequests, serve(w, http.MethodPost, "/deploy", "", nil).Code)
+}
+
+func TestWebhookFailed(t *testing.T) {
+	w := NewWebhook(logrus.New())
+
+	w.HandleFunc(&config.Webhook{Path: "/deploy"}, func(ev event.Event) error {
+		return errors.New("template: missing var")
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, serve(w, http.MethodPost, "/deploy", "", nil).Code)
+}
+
+func TestWebhookNotRun(t *testing.T) {
+	w := NewWebhook(logrus.New())
+
+	w.HandleFunc(&config.Webhook{Path: "/deploy"}, func(ev event.Event) error {
+		return &executor.NotRunError{Reason: "service is paused"}
+	})
+
+	response := serve(w, http.MethodPost, "/deploy", "", nil)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "Not run: service is paused\n", response.Body.String())
+
+	//The event is accepted if any condition queued it
+	w.HandleFunc(&config.Webhook{Path: "/deploy"}, func(ev event.Event) error {
+		return nil
+	})
+
+	assert.Equal(t, http.StatusAccepted, serve(w, http.MethodPost, "/deploy", "", nil).Code)
+}
+
+func TestWebhookRemove(t *testing.T) {
+	w := NewWebhook(logrus.New())
+
+	id := w.HandleFunc(&config.Webhook{Path: "/deploy"}, func(ev event.Event) error {
+		return nil
+	})
+	w.Remove(id)
+
+	assert.Equal(t, http.StatusNotFound, serve(w, http.MethodPost, "/deploy", "", nil).Code)
+}
+
+func TestWebhookToken(t *testing.T) {
+	w := NewWebhook(logrus.New())
+
+	received := make(chan event.Event, 1)
+	w.HandleFunc(&config.Webhook{Path: "/deploy", Secret: "s3cret", Verify: config.Token}, func(ev event.Event) error {
+		received <- ev
+		return nil
+	})
+
+	assert.Equal(t, http.StatusUnauthorized, serve(w, http.MethodPost, "/deploy", "", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(w, http.MethodPost, "/deploy", "", map[string]string{
+		DefaultTokenHeader: "wrong",
+	}).Code)
+	assert.Equal(t, http.StatusAccepted, serve(w, http.MethodPost, "/deploy", "", map[string]string{
+		DefaultTokenHeader: "s3cret",
+		"X-Request-Id":     "1",
+	}).Code)
+
+	ev := <-received
+	assert.Empty(t, ev.Headers.Get(DefaultTokenHeader))
+	assert.Equal(t, "1", ev.Headers.Get("X-Request-Id"))
+}
+
+func TestWebhookHmac(t *testing.T) {
+	w := NewWebhook(logrus.New())
+
+	w.HandleFunc(&config.Webhook{Path: "/deploy", Secret: "s3cret", Verify: config.HmacSha256}, func(ev event.Event) error {
+		return nil
+	})
+
+	body := `{"ref": "main"}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	assert.Equal(t, http.StatusAccepted, serve(w, http.MethodPost, "/deploy", body, map[string]string{
+		DefaultSignatureHeader: signature,
+	}).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(w, http.MethodPost, "/deploy", body+" ", map[string]string{
+		DefaultSignatureHeader: signature,
+	}).Code)
+}